@@ -0,0 +1,43 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"encoding/json"
+)
+
+// AdminRequest is an admin command received on the control pipe or the
+// admin HTTP endpoint.
+type AdminRequest struct {
+	Command string
+	Args    []string
+
+	pipe uintptr // server end of the control pipe the request came from
+}
+
+// AdminHandler handles one admin command. The result is sent back to the
+// client JSON encoded.
+type AdminHandler func(req *AdminRequest) (interface{}, error)
+
+// defaultPipeSDDL gives access to the control pipe to LocalSystem and
+// Administrators only.
+const defaultPipeSDDL = "D:P(A;;GA;;;SY)(A;;GA;;;BA)"
+
+// ControlPipeName returns the path of the control pipe of service name.
+func ControlPipeName(name string) string {
+	return `\\.\pipe\winsvc-` + name
+}
+
+// adminMessage and adminReply are the JSON messages exchanged with
+// admin clients.
+type adminMessage struct {
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+}
+
+type adminReply struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
@@ -0,0 +1,93 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// BearerTokenAuth returns an auth hook for WithAdminHTTP which accepts
+// requests carrying "Authorization: Bearer <token>". An empty token
+// accepts no request.
+func BearerTokenAuth(token string) func(r *http.Request) error {
+	return func(r *http.Request) error {
+		if token == "" {
+			return errors.New("winsvc: unauthorized")
+		}
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) != 1 {
+			return errors.New("winsvc: unauthorized")
+		}
+		return nil
+	}
+}
+
+// AdminClient talks to the admin endpoint of a service (see WithAdminHTTP).
+type AdminClient struct {
+	BaseURL string       // such as "http://127.0.0.1:9090"
+	Token   string       // sent as a bearer token if not empty
+	Client  *http.Client // http.DefaultClient if nil
+}
+
+// Call runs command on the service and returns its JSON encoded result.
+func (c *AdminClient) Call(command string, args ...string) (json.RawMessage, error) {
+	body, err := json.Marshal(adminMessage{Args: args})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(c.BaseURL, "/")+"/"+command, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("winsvc.AdminClient: %s", resp.Status)
+	}
+	var reply adminReply
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("winsvc.AdminClient: %s: %v", resp.Status, err)
+	}
+	if reply.Error != "" {
+		return nil, errors.New(reply.Error)
+	}
+	return reply.Result, nil
+}
+
+func (c *AdminClient) Status() (json.RawMessage, error) {
+	return c.Call("status")
+}
+
+func (c *AdminClient) Reload() error {
+	_, err := c.Call("reload")
+	return err
+}
+
+func (c *AdminClient) Drain() error {
+	_, err := c.Call("drain")
+	return err
+}
+
+func (c *AdminClient) Shutdown() error {
+	_, err := c.Call("shutdown")
+	return err
+}
@@ -0,0 +1,176 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// adminHandlers returns the built-in admin commands, plus or replaced by
+// the commands in extra.
+func (p *serviceRuntime) adminHandlers(extra map[string]AdminHandler) map[string]AdminHandler {
+	handlers := map[string]AdminHandler{
+		"status":      p.adminStatus,
+		"diagnostics": p.adminDiagnostics,
+		"shutdown":    p.adminShutdown,
+	}
+	if p.opts.reload != nil {
+		handlers["reload"] = p.adminReload
+	}
+	if p.opts.drain != nil {
+		handlers["drain"] = p.adminDrain
+	}
+	for k, v := range extra {
+		handlers[k] = v
+	}
+	if h := p.opts.hook; h != nil {
+		for k, v := range handlers {
+			handlers[k] = hookedHandler(h, k, v)
+		}
+	}
+	return handlers
+}
+
+func hookedHandler(hook LifecycleHook, name string, h AdminHandler) AdminHandler {
+	return func(req *AdminRequest) (interface{}, error) {
+		t := time.Now()
+		result, err := h(req)
+		hook.Command(name, time.Since(t), err)
+		return result, err
+	}
+}
+
+func dispatchAdmin(handlers map[string]AdminHandler, req *AdminRequest) (reply adminReply) {
+	h, ok := handlers[req.Command]
+	if !ok {
+		reply.Error = fmt.Sprintf("winsvc: unknown command %q", req.Command)
+		return
+	}
+	result, err := h(req)
+	if err != nil {
+		reply.Error = err.Error()
+		return
+	}
+	if reply.Result, err = json.Marshal(result); err != nil {
+		reply.Error = err.Error()
+	}
+	return
+}
+
+func (p *serviceRuntime) adminStatus(req *AdminRequest) (interface{}, error) {
+	status := p.currentStatus()
+	return map[string]interface{}{
+		"name":       p.serviceName(),
+		"state":      stateString(status.State),
+		"checkPoint": status.CheckPoint,
+		"pid":        os.Getpid(),
+	}, nil
+}
+
+func (p *serviceRuntime) adminDiagnostics(req *AdminRequest) (interface{}, error) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return map[string]interface{}{
+		"name":       p.serviceName(),
+		"pid":        os.Getpid(),
+		"uptime":     time.Since(p.started).String(),
+		"goroutines": runtime.NumGoroutine(),
+		"heapAlloc":  ms.HeapAlloc,
+		"sys":        ms.Sys,
+		"numGC":      ms.NumGC,
+		"goVersion":  runtime.Version(),
+	}, nil
+}
+
+func (p *serviceRuntime) adminShutdown(req *AdminRequest) (interface{}, error) {
+	p.requestStop()
+	return "stopping", nil
+}
+
+func (p *serviceRuntime) adminReload(req *AdminRequest) (interface{}, error) {
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	return "reloaded", nil
+}
+
+func (p *serviceRuntime) adminDrain(req *AdminRequest) (interface{}, error) {
+	p.requestDrain()
+	return "draining", nil
+}
+
+type adminServer struct {
+	srv *http.Server
+	ln  net.Listener
+}
+
+// serveAdminHTTP serves the admin commands as POST /<command> requests
+// on the configured loopback address.
+func (p *serviceRuntime) serveAdminHTTP() (*adminServer, error) {
+	opts := p.opts.adminHTTP
+	if opts.auth == nil {
+		return nil, fmt.Errorf("winsvc: admin endpoint %s has no auth hook", opts.addr)
+	}
+	host, _, err := net.SplitHostPort(opts.addr)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("winsvc: admin address %s is not a loopback address", opts.addr)
+	}
+	ln, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return nil, err
+	}
+	handlers := p.adminHandlers(opts.handlers)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// commands change the state of the service: a GET, such as
+		// the <img> of a web page, must not run them
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := opts.auth(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		var msg adminMessage
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		req := &AdminRequest{Command: strings.Trim(r.URL.Path, "/"), Args: msg.Args}
+		reply := dispatchAdmin(handlers, req)
+		w.Header().Set("Content-Type", "application/json")
+		if reply.Error != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		json.NewEncoder(w).Encode(reply)
+	})
+	s := &adminServer{srv: &http.Server{Handler: mux}, ln: ln}
+	go s.srv.Serve(ln)
+	p.elog.Info(1, fmt.Sprintf("winsvc.Execute: admin endpoint listening on %s", ln.Addr()))
+	return s, nil
+}
+
+func (s *adminServer) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.srv.Shutdown(ctx)
+}
@@ -0,0 +1,84 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// AuditEvent records a management operation made through this package.
+type AuditEvent struct {
+	Time      time.Time
+	User      string // account of the process making the change, DOMAIN\user
+	Host      string // remote host of the Manager, empty for the local machine
+	Operation string // Install, Remove, Update, SetSecurity, Start or Stop
+	Service   string
+
+	// Old and New are the configuration before and after an Install or
+	// Update, without the password.
+	Old, New *ServiceConfig
+
+	// StopReason is the reason given for a Stop, nil if none was.
+	StopReason *StopReason `json:",omitempty"`
+
+	Err error // why the operation failed, nil if it succeeded
+}
+
+// MarshalJSON encodes e with Err as a string.
+func (e AuditEvent) MarshalJSON() ([]byte, error) {
+	type event AuditEvent
+	v := struct {
+		event
+		Err string `json:",omitempty"`
+	}{event: event(e)}
+	if e.Err != nil {
+		v.Err = e.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+var auditHook struct {
+	sync.Mutex
+	fn func(e AuditEvent)
+}
+
+// SetAuditHook makes every management operation call fn with its audit
+// event once it is done. A nil fn disables auditing.
+func SetAuditHook(fn func(e AuditEvent)) {
+	auditHook.Lock()
+	defer auditHook.Unlock()
+	auditHook.fn = fn
+}
+
+// AuditLogger returns an audit hook writing the events to l as JSON,
+// such as to the event log with OpenEventLogger. Failed operations are
+// logged as warnings.
+func AuditLogger(l Logger) func(e AuditEvent) {
+	return func(e AuditEvent) {
+		msg, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		level := LevelInfo
+		if e.Err != nil {
+			level = LevelWarning
+		}
+		l.Log(level, 1, string(msg))
+	}
+}
+
+func currentAuditHook() func(e AuditEvent) {
+	auditHook.Lock()
+	defer auditHook.Unlock()
+	return auditHook.fn
+}
+
+// auditConfig returns a copy of cfg safe to audit.
+func auditConfig(cfg ServiceConfig) *ServiceConfig {
+	cfg.Password = ""
+	return &cfg
+}
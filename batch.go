@@ -0,0 +1,185 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceSpec describes one service of a batch operation. The order of
+// the batch follows Config.Dependencies between services of the batch.
+type ServiceSpec struct {
+	Name    string
+	AppPath string
+	Config  ServiceConfig
+}
+
+// Result is the outcome of a batch operation for one service.
+type Result struct {
+	Name string
+	Err  error
+}
+
+// batchTimeout is how long StartAll and StopAll wait for each service
+// to run or stop.
+const batchTimeout = 30 * time.Second
+
+func InstallAll(specs []ServiceSpec) ([]Result, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.InstallAll(specs)
+}
+
+func StartAll(specs []ServiceSpec) ([]Result, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.StartAll(specs)
+}
+
+func StopAll(specs []ServiceSpec) ([]Result, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.StopAll(specs)
+}
+
+// InstallAll installs the services, each after the ones it depends on.
+// The results are in the order of specs; the error tells whether any failed.
+func (p *Manager) InstallAll(specs []ServiceSpec) ([]Result, error) {
+	return runBatch(specs, false, func(s ServiceSpec) error {
+		return p.InstallWithConfig(s.AppPath, s.Name, s.Config)
+	})
+}
+
+// StartAll starts the services, each one once the ones it depends on
+// are running. Services already running are left alone.
+func (p *Manager) StartAll(specs []ServiceSpec) ([]Result, error) {
+	return runBatch(specs, false, func(s ServiceSpec) error {
+		if state, err := p.Query(s.Name); err == nil && state == "Running" {
+			return nil
+		}
+		return p.StartAndWait(s.Name, batchTimeout)
+	})
+}
+
+// StopAll stops the services, each one once the services of the batch
+// depending on it are stopped. Services already stopped are left alone.
+func (p *Manager) StopAll(specs []ServiceSpec) ([]Result, error) {
+	return runBatch(specs, true, func(s ServiceSpec) error {
+		if state, err := p.Query(s.Name); err == nil && state == "Stopped" {
+			return nil
+		}
+		return p.StopAndWait(s.Name, batchTimeout)
+	})
+}
+
+// runBatch runs op for every spec, concurrently unless one depends on the
+// other, in dependency order or in reverse order. A spec whose dependency
+// failed is not run.
+func runBatch(specs []ServiceSpec, reverse bool, op func(s ServiceSpec) error) ([]Result, error) {
+	after, err := batchOrder(specs, reverse)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(specs))
+	done := make([]chan struct{}, len(specs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	var wg sync.WaitGroup
+	for i := range specs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+			results[i].Name = specs[i].Name
+			for _, j := range after[i] {
+				<-done[j]
+				if results[j].Err != nil {
+					results[i].Err = fmt.Errorf("winsvc: %s skipped, %s failed", specs[i].Name, specs[j].Name)
+					return
+				}
+			}
+			results[i].Err = op(specs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("winsvc: %d of %d services failed: %s", len(failed), len(specs), strings.Join(failed, ", "))
+	}
+	return results, nil
+}
+
+// batchOrder returns, for every spec, the specs which must be done before
+// it, and fails if the dependencies form a cycle.
+func batchOrder(specs []ServiceSpec, reverse bool) ([][]int, error) {
+	index := make(map[string]int, len(specs))
+	for i, s := range specs {
+		index[strings.ToLower(s.Name)] = i
+	}
+	after := make([][]int, len(specs))
+	for i, s := range specs {
+		for _, dep := range s.Config.Dependencies {
+			j, ok := index[strings.ToLower(dep)]
+			if !ok || j == i {
+				continue
+			}
+			if reverse {
+				after[j] = append(after[j], i)
+			} else {
+				after[i] = append(after[i], j)
+			}
+		}
+	}
+
+	// check for cycles, which would make runBatch wait forever
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(specs))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("winsvc: dependency cycle through %s", specs[i].Name)
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		for _, j := range after[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		return nil
+	}
+	for i := range specs {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return after, nil
+}
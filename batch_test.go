@@ -0,0 +1,81 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// batchSpecs are a web service depending on an api service, which
+// depends on a db service, and a cache service on its own.
+var batchSpecs = []ServiceSpec{
+	{Name: "web", Config: ServiceConfig{Dependencies: []string{"API"}}},
+	{Name: "api", Config: ServiceConfig{Dependencies: []string{"db", "Tcpip"}}},
+	{Name: "db"},
+	{Name: "cache"},
+}
+
+// runOrder runs a batch of batchSpecs, and returns the position each
+// service ran at.
+func runOrder(reverse bool, fail string) (map[string]int, []Result) {
+	var mu sync.Mutex
+	pos := map[string]int{}
+	results, _ := runBatch(batchSpecs, reverse, func(s ServiceSpec) error {
+		mu.Lock()
+		defer mu.Unlock()
+		pos[s.Name] = len(pos)
+		if s.Name == fail {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	return pos, results
+}
+
+func TestBatchOrder(t *testing.T) {
+	pos, _ := runOrder(false, "")
+	if len(pos) != len(batchSpecs) {
+		t.Fatalf("ran %d services, want %d", len(pos), len(batchSpecs))
+	}
+	if !(pos["db"] < pos["api"] && pos["api"] < pos["web"]) {
+		t.Errorf("install and start order %v does not follow the dependencies", pos)
+	}
+}
+
+func TestBatchOrderReverse(t *testing.T) {
+	pos, _ := runOrder(true, "")
+	if len(pos) != len(batchSpecs) {
+		t.Fatalf("ran %d services, want %d", len(pos), len(batchSpecs))
+	}
+	if !(pos["web"] < pos["api"] && pos["api"] < pos["db"]) {
+		t.Errorf("stop order %v does not follow the reverse dependencies", pos)
+	}
+}
+
+func TestBatchSkipsDependents(t *testing.T) {
+	pos, results := runOrder(false, "db")
+	for _, name := range []string{"api", "web"} {
+		if _, ok := pos[name]; ok {
+			t.Errorf("%s ran although db failed", name)
+		}
+	}
+	for _, r := range results {
+		if failed := r.Err != nil; failed != (r.Name != "cache") {
+			t.Errorf("%s: error %v", r.Name, r.Err)
+		}
+	}
+}
+
+func TestBatchCycle(t *testing.T) {
+	specs := []ServiceSpec{
+		{Name: "a", Config: ServiceConfig{Dependencies: []string{"b"}}},
+		{Name: "b", Config: ServiceConfig{Dependencies: []string{"a"}}},
+	}
+	if _, err := runBatch(specs, false, func(ServiceSpec) error { return nil }); err == nil {
+		t.Fatal("runBatch of a dependency cycle returned nil error")
+	}
+}
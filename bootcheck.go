@@ -0,0 +1,143 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+func DiagnoseBootStart(name string) (*BootReport, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.DiagnoseBootStart(name)
+}
+
+// DiagnoseBootStart checks service name for the usual reasons of an
+// automatic service not starting at boot: a start type which does not
+// start it, a missing binary, a missing or disabled dependency, an
+// account without the "Log on as a service" right, and the exit code of
+// its last run. It fails only if the service cannot be read.
+func (p *Manager) DiagnoseBootStart(name string) (*BootReport, error) {
+	cfg, err := p.GetServiceConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	status, err := p.QueryStatus(name)
+	if err != nil {
+		return nil, err
+	}
+	r := &BootReport{Service: name, StartType: cfg.StartType.String(), State: status.State}
+	switch cfg.StartType {
+	case StartTypeManual:
+		if len(cfg.Triggers) > 0 {
+			r.add("StartType", "the service is started by its triggers only, not at boot")
+		} else {
+			r.add("StartType", "the service is started on demand only, not at boot")
+		}
+	case StartTypeDisabled:
+		r.add("StartType", "the service is disabled")
+	}
+	if p.host == "" {
+		checkBinary(r, cfg)
+	}
+	p.checkDependencies(r, name, cfg, map[string]bool{strings.ToLower(name): true})
+	p.checkLogonRight(r, cfg.Account)
+	checkLastExit(r, status)
+	return r, nil
+}
+
+func checkBinary(r *BootReport, cfg ServiceConfig) {
+	path, err := expandServicePath(cfg.BinaryPath)
+	if err != nil {
+		path = cfg.BinaryPath
+	}
+	if _, err := os.Stat(nativePath(path)); err != nil {
+		if len(cfg.Args) > 0 && !strings.Contains(cfg.BinaryPath, " ") {
+			r.add("Binary", "%s: %v (a path with spaces must be quoted)", path, err)
+			return
+		}
+		r.add("Binary", "%s: %v", path, err)
+	}
+}
+
+// checkDependencies checks the dependencies of service chain, and theirs.
+func (p *Manager) checkDependencies(r *BootReport, chain string, cfg ServiceConfig, seen map[string]bool) {
+	for _, dep := range cfg.Dependencies {
+		if strings.HasPrefix(dep, "+") || seen[strings.ToLower(dep)] {
+			continue // load ordering groups are not checked
+		}
+		seen[strings.ToLower(dep)] = true
+		path := chain + " -> " + dep
+		dc, err := p.GetServiceConfig(dep)
+		if err != nil {
+			r.add("Dependency", "%s: not installed or not readable", path)
+			continue
+		}
+		switch {
+		case dc.StartType == StartTypeDisabled:
+			r.add("Dependency", "%s: %s is disabled, so %s cannot start", path, dep, r.Service)
+		case dc.StartType == StartTypeAutomaticDelayed && cfg.StartType == StartTypeAutomatic:
+			r.add("Dependency", "%s: %s is delayed, but starts early with the automatic services depending on it", path, dep)
+		}
+		if status, err := p.QueryStatus(dep); err == nil && status.State == "Stopped" && status.Win32ExitCode != 0 {
+			r.add("Dependency", "%s: %s stopped with exit code %d", path, dep, status.Win32ExitCode)
+		}
+		p.checkDependencies(r, path, dc, seen)
+	}
+}
+
+func (p *Manager) checkLogonRight(r *BootReport, account string) {
+	if !needsLogonRight(account) {
+		return
+	}
+	sid, err := lookupAccountSID(p.host, account)
+	if err != nil {
+		r.add("Account", "%v", err)
+		return
+	}
+	policy, err := openPolicy(p.host, policyLookupNames)
+	if err != nil {
+		r.add("Account", "could not read the rights of %s: %v", account, err)
+		return
+	}
+	defer closePolicy(policy)
+	rights, err := accountRights(policy, sid)
+	if err != nil {
+		r.add("Account", "could not read the rights of %s: %v", account, err)
+		return
+	}
+	if hasRight(rights, rightDenyServiceLogon) {
+		r.add("Account", "%s is denied the right to log on as a service", account)
+	}
+	if !hasRight(rights, rightServiceLogon) {
+		r.add("Account", "%s is not granted the right to log on as a service directly (it may have it through a group)", account)
+	}
+}
+
+func checkLastExit(r *BootReport, status ServiceStatus) {
+	if status.State != "Stopped" || status.Win32ExitCode == 0 {
+		return
+	}
+	switch windows.Errno(status.Win32ExitCode) {
+	case windows.ERROR_SERVICE_REQUEST_TIMEOUT:
+		r.add("LastExit", "the service did not report Running in time; report start progress (see WithWaitHints)")
+	case windows.ERROR_SERVICE_LOGON_FAILED:
+		r.add("LastExit", "the service could not log on: wrong password or missing right")
+	case windows.ERROR_SERVICE_DEPENDENCY_FAIL:
+		r.add("LastExit", "a dependency of the service failed to start")
+	case errorServiceSpecificError:
+		r.add("LastExit", "the service stopped with service specific exit code %d", status.ServiceSpecificExitCode)
+	default:
+		r.add("LastExit", "the service stopped with exit code %d: %v", status.Win32ExitCode, windows.Errno(status.Win32ExitCode))
+	}
+}
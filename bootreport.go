@@ -0,0 +1,45 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BootFinding is a likely reason for a service not to start at boot.
+type BootFinding struct {
+	Check   string `json:"check"` // StartType, Binary, Dependency, Account or LastExit
+	Problem string `json:"problem"`
+}
+
+// BootReport is the outcome of DiagnoseBootStart.
+type BootReport struct {
+	Service   string        `json:"service"`
+	StartType string        `json:"startType"`
+	State     string        `json:"state"`
+	Findings  []BootFinding `json:"findings"`
+}
+
+// OK reports whether no problem was found.
+func (r *BootReport) OK() bool {
+	return len(r.Findings) == 0
+}
+
+func (r *BootReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s, %s):", r.Service, r.StartType, r.State)
+	if r.OK() {
+		b.WriteString(" no problem found")
+	}
+	for _, f := range r.Findings {
+		fmt.Fprintf(&b, "\n  %s: %s", f.Check, f.Problem)
+	}
+	return b.String()
+}
+
+func (r *BootReport) add(check, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, BootFinding{Check: check, Problem: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,98 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"time"
+)
+
+// Bundle is the state of a set of services, taken by ExportServices to
+// set them up again on another computer with ImportServices. It can be
+// stored as JSON.
+type Bundle struct {
+	Exported time.Time
+	Services []BundleService
+}
+
+// BundleService is the state of one service of a Bundle: its
+// configuration, with the recovery actions and triggers, and the string
+// values of its Parameters registry key. Passwords cannot be read back,
+// so Config.Password must be set again before importing a service
+// running as an account which has one.
+type BundleService struct {
+	Name       string
+	Config     ServiceConfig
+	Parameters map[string]string
+}
+
+func ExportServices(names ...string) (Bundle, error) {
+	m, err := Connect()
+	if err != nil {
+		return Bundle{}, err
+	}
+	defer m.Disconnect()
+	return m.ExportServices(names...)
+}
+
+func ImportServices(b Bundle) ([]Result, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.ImportServices(b)
+}
+
+// ExportServices returns the state of services names.
+func (p *Manager) ExportServices(names ...string) (Bundle, error) {
+	b := Bundle{Exported: time.Now()}
+	for _, name := range names {
+		cfg, err := p.GetServiceConfig(name)
+		if err != nil {
+			return Bundle{}, fmt.Errorf("winsvc.ExportServices: %v", err)
+		}
+		params, err := p.GetParameters(name)
+		if err != nil {
+			return Bundle{}, fmt.Errorf("winsvc.ExportServices: %v", err)
+		}
+		b.Services = append(b.Services, BundleService{Name: name, Config: cfg, Parameters: params})
+	}
+	return b, nil
+}
+
+// ImportServices sets up the services of b, each after the ones it
+// depends on: services already installed are reconfigured (see
+// UpdateService), the others are installed, with the binary path they
+// had when exported, which must exist on this computer. Then their
+// Parameters are stored, keeping the values b does not have. The results
+// are in the order of b.Services; the error tells whether any failed.
+func (p *Manager) ImportServices(b Bundle) ([]Result, error) {
+	specs := make([]ServiceSpec, len(b.Services))
+	params := make(map[string]map[string]string, len(b.Services))
+	for i, s := range b.Services {
+		specs[i] = ServiceSpec{Name: s.Name, Config: s.Config}
+		params[s.Name] = s.Parameters
+	}
+	results, err := runBatch(specs, false, func(s ServiceSpec) error {
+		var err error
+		if _, qerr := p.Query(s.Name); qerr != nil {
+			err = p.InstallWithConfig("", s.Name, s.Config)
+		} else {
+			err = p.UpdateService("", s.Name, s.Config)
+		}
+		if err != nil {
+			return err
+		}
+		if len(params[s.Name]) > 0 {
+			return p.SetParameters(s.Name, params[s.Name])
+		}
+		return nil
+	})
+	if err != nil {
+		return results, fmt.Errorf("winsvc.ImportServices: %v", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+var detected struct {
+	once sync.Once
+	caps Capabilities
+}
+
+// DetectCapabilities returns the optional features of this computer
+// (see Capabilities), looked up once.
+func DetectCapabilities() Capabilities {
+	detected.once.Do(func() {
+		c := &detected.caps
+		if k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, registry.QUERY_VALUE|keyView); err == nil {
+			c.InstallationType, _, _ = k.GetStringValue("InstallationType")
+			k.Close()
+		}
+		if h, err := windows.RegisterEventSource(nil, windows.StringToUTF16Ptr("Application")); err == nil {
+			windows.DeregisterEventSource(h)
+			c.EventLog = true
+		}
+		if dir, err := windows.GetSystemDirectory(); err == nil {
+			_, err = os.Stat(nativePath(filepath.Join(dir, "EventCreate.exe")))
+			c.EventCreate = err == nil
+		}
+		c.Shell = windows.NewLazySystemDLL("shlwapi.dll").Load() == nil
+		c.CrashDumps = moddbghelp.Load() == nil
+		c.Sessions = windows.NewLazySystemDLL("wtsapi32.dll").Load() == nil
+	})
+	return detected.caps
+}
+
+// capabilities returns the features the package may use, following
+// Options.Compatibility.
+func capabilities() Capabilities {
+	switch Defaults().Compatibility {
+	case CompatFull:
+		return Capabilities{InstallationType: DetectCapabilities().InstallationType, EventLog: true, EventCreate: true, Shell: true, CrashDumps: true, Sessions: true}
+	case CompatMinimal:
+		return Capabilities{InstallationType: DetectCapabilities().InstallationType, EventLog: DetectCapabilities().EventLog}
+	}
+	return DetectCapabilities()
+}
+
+// fallbackLogger returns the Logger of service name when the event log
+// cannot be opened: a file in the ProgramData directory of the service.
+func fallbackLogger(name string) (Logger, error) {
+	dir := filepath.Join(os.Getenv("ProgramData"), name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return NewFileLogger(filepath.Join(dir, name+".log"), 10<<20, 3)
+}
+
+// degrade disables the options using features this computer lacks.
+func (p *serviceRuntime) degrade() {
+	caps := capabilities()
+	if p.opts.crashDump && !caps.CrashDumps {
+		p.opts.crashDump = false
+		p.elog.Warning(1, "winsvc.RunAsService: crash dumps disabled, dbghelp.dll is not available")
+	}
+	if p.opts.sessionHelper != nil && !caps.Sessions {
+		p.opts.sessionHelper = nil
+		p.elog.Warning(1, fmt.Sprintf("winsvc.RunAsService: session helpers disabled, this %s installation has no user sessions", caps.InstallationType))
+	}
+}
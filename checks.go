@@ -0,0 +1,60 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// CheckFailedExitCode is the service specific exit code of a service
+// stopped by a failed pre-start check (see WithCheck), unless the error
+// of the check carries its own exit code.
+const CheckFailedExitCode = 2
+
+// CheckError reports that pre-start check Name failed (see WithCheck).
+type CheckError struct {
+	Name string
+	Err  error
+}
+
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("winsvc: pre-start check %s failed: %v", e.Name, e.Err)
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+// CheckListen returns a pre-start check that address is free to listen
+// on network, such as "tcp" and ":8080".
+func CheckListen(network, address string) func() error {
+	return func() error {
+		l, err := net.Listen(network, address)
+		if err != nil {
+			return err
+		}
+		return l.Close()
+	}
+}
+
+// CheckFile returns a pre-start check that the file at path exists and,
+// if parse is not nil, that parse accepts its content, such as a
+// configuration file or a license.
+func CheckFile(path string, parse func(data []byte) error) func() error {
+	return func() error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if parse != nil {
+			if err := parse(data); err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// CommandLineUsage describes the commands of HandleCommandLine.
+const CommandLineUsage = `Commands:
+  install   install the service [-start] [-timeout d]
+  remove    remove the service
+  start     start the service [-timeout d]
+  stop      stop the service [-timeout d]
+  status    show the state of the service [-tree]
+  reload    ask the service to reload its configuration
+  drain     finish the work in flight, ahead of a stop [-timeout d]
+
+Every command takes -json to print its result, with the status of the
+service, as a JSON object.
+`
+
+// HandleCommandLine runs the service management command named by args[0],
+// such as "install" or "start", on service name configured as cfg, and
+// reports whether args was such a command. Otherwise the caller goes on
+// to run the service:
+//
+//	if handled, err := winsvc.HandleCommandLine(name, cfg, os.Args[1:]); handled {
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		return
+//	}
+//	winsvc.RunAsServiceContext(name, run, winsvc.IsAnInteractiveSession())
+func HandleCommandLine(name string, cfg ServiceConfig, args []string) (handled bool, err error) {
+	return handleCommandLine(os.Stdout, os.Stderr, name, cfg, args)
+}
+
+// handleCommandLine is HandleCommandLine printing the results to w and
+// the flag errors and usage to errw, so that they do not get mixed with
+// the -json output.
+func handleCommandLine(w, errw io.Writer, name string, cfg ServiceConfig, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(errw)
+	timeout := Defaults().Timeout
+	var start, tree bool
+	switch args[0] {
+	case "install":
+		fs.BoolVar(&start, "start", false, "start the service once installed")
+		fs.DurationVar(&timeout, "timeout", timeout, "how long to wait for the service to start")
+	case "start", "stop", "drain":
+		fs.DurationVar(&timeout, "timeout", timeout, "how long to wait for the service to "+args[0])
+	case "status":
+		fs.BoolVar(&tree, "tree", false, "also show the dependencies and the dependents of the service")
+	}
+	jsonOut := fs.Bool("json", false, "print the result as JSON")
+	switch args[0] {
+	case "install", "remove", "start", "stop", "status", "reload", "drain":
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(w, CommandLineUsage)
+		return true, nil
+	default:
+		return false, nil
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return true, err
+	}
+
+	began := time.Now()
+	if tree {
+		root, err := statusTree(name)
+		if *jsonOut {
+			return true, writeStatusTreeResult(w, name, began, root, err)
+		}
+		if err != nil {
+			return true, err
+		}
+		writeStatusTree(w, root)
+		return true, nil
+	}
+
+	err := runCommand(args[0], name, cfg, start, timeout)
+	if *jsonOut {
+		return true, writeCommandResult(w, args[0], name, began, err)
+	}
+	if err != nil {
+		return true, err
+	}
+	if args[0] == "status" {
+		state, err := QueryService(name)
+		if err != nil {
+			return true, err
+		}
+		fmt.Fprintf(w, "%s: %s\n", name, state)
+		return true, nil
+	}
+	fmt.Fprintf(w, "%s: %s done\n", name, args[0])
+	return true, nil
+}
+
+// runCommand runs management command cmd on service name. start tells
+// install to start the service too.
+func runCommand(cmd, name string, cfg ServiceConfig, start bool, timeout time.Duration) error {
+	switch cmd {
+	case "install":
+		appPath, err := GetAppPath()
+		if err != nil {
+			return err
+		}
+		if start {
+			return InstallAndStartService(appPath, name, cfg, timeout)
+		}
+		return InstallServiceWithConfig(appPath, name, cfg)
+	case "remove":
+		return RemoveService(name)
+	case "start":
+		return StartServiceAndWait(name, timeout)
+	case "stop":
+		return StopServiceTimeout(name, timeout)
+	case "reload":
+		return ReloadService(name)
+	case "drain":
+		return DrainService(name, timeout)
+	}
+	return nil
+}
+
+// commandResult is the outcome of a command printed with -json.
+type commandResult struct {
+	Service   string         `json:"service"`
+	Command   string         `json:"command"`
+	OK        bool           `json:"ok"`
+	Error     string         `json:"error,omitempty"`
+	ElapsedMs int64          `json:"elapsedMs"`
+	Status    *ServiceStatus `json:"status,omitempty"` // after the command, unless removed
+	Tree      *statusNode    `json:"tree,omitempty"`   // status -tree
+}
+
+// writeStatusTreeResult writes the outcome of status -tree to w as JSON,
+// and returns err.
+func writeStatusTreeResult(w io.Writer, name string, began time.Time, root *statusNode, err error) error {
+	r := commandResult{
+		Service:   name,
+		Command:   "status",
+		OK:        err == nil,
+		ElapsedMs: int64(time.Since(began) / time.Millisecond),
+		Tree:      root,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	} else {
+		r.Status = &root.ServiceStatus
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if werr := enc.Encode(r); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
+
+// writeCommandResult writes the outcome err of command cmd to w as JSON,
+// and returns err.
+func writeCommandResult(w io.Writer, cmd, name string, began time.Time, err error) error {
+	r := commandResult{
+		Service:   name,
+		Command:   cmd,
+		OK:        err == nil,
+		ElapsedMs: int64(time.Since(began) / time.Millisecond),
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if cmd != "remove" || err != nil {
+		if status, qerr := QueryServiceStatus(name); qerr == nil {
+			r.Status = &status
+		} else if err == nil && cmd == "status" {
+			err = qerr
+			r.OK, r.Error = false, qerr.Error()
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if werr := enc.Encode(r); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// The -json output of a failed command is one JSON object, with the
+// error in it.
+func TestCommandLineJSON(t *testing.T) {
+	const name = "winsvc-test-no-such-service"
+	var out, errOut bytes.Buffer
+	handled, err := handleCommandLine(&out, &errOut, name, ServiceConfig{}, []string{"status", "-json"})
+	if !handled || err == nil {
+		t.Fatalf("handled %v, error %v, want handled with an error", handled, err)
+	}
+	var r commandResult
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatalf("output %q is not JSON: %v", out.String(), err)
+	}
+	if r.Service != name || r.Command != "status" || r.OK || r.Error == "" {
+		t.Errorf("got result %+v, want a failed status of %s", r, name)
+	}
+	if errOut.Len() != 0 {
+		t.Errorf("wrote %q to the error output, want nothing", errOut.String())
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCommandLineParseError(t *testing.T) {
+	for _, args := range [][]string{
+		{"stop", "-json", "-timeout", "soon"},
+		{"status", "-bogus"},
+	} {
+		var out, errOut bytes.Buffer
+		handled, err := handleCommandLine(&out, &errOut, "svc", ServiceConfig{}, args)
+		if !handled || err == nil {
+			t.Errorf("%q: handled %v, error %v, want handled with an error", args, handled, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%q: wrote %q to the output, want nothing", args, out.String())
+		}
+		if !strings.Contains(errOut.String(), "Usage") {
+			t.Errorf("%q: wrote %q to the error output, want the usage", args, errOut.String())
+		}
+	}
+}
+
+func TestCommandLineNotHandled(t *testing.T) {
+	for _, args := range [][]string{nil, {"-debug"}, {"run"}} {
+		var out, errOut bytes.Buffer
+		if handled, err := handleCommandLine(&out, &errOut, "svc", ServiceConfig{}, args); handled || err != nil {
+			t.Errorf("%q: handled %v, error %v, want not handled", args, handled, err)
+		}
+		if out.Len() != 0 || errOut.Len() != 0 {
+			t.Errorf("%q: wrote %q and %q, want nothing", args, out.String(), errOut.String())
+		}
+	}
+}
+
+func TestCommandLineHelp(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if handled, err := handleCommandLine(&out, &errOut, "svc", ServiceConfig{}, []string{"help"}); !handled || err != nil {
+		t.Fatalf("help: handled %v, error %v", handled, err)
+	}
+	if out.String() != CommandLineUsage {
+		t.Errorf("help wrote %q, want the command usage", out.String())
+	}
+}
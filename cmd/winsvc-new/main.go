@@ -0,0 +1,179 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Winsvc-new writes the skeleton of a Windows service using package
+// winsvc: a main.go handling the install/remove/start/stop commands and
+// stopping gracefully, and a service.json manifest holding the service
+// configuration.
+//
+// Usage:
+//
+//	go run github.com/chai2010/winsvc/cmd/winsvc-new -name myservice [-dir myservice] [-module example.com/myservice]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/chai2010/winsvc"
+)
+
+var (
+	flagName   = flag.String("name", "", "service name (required)")
+	flagDesc   = flag.String("desc", "", "service display name (default: the name)")
+	flagDir    = flag.String("dir", "", "output directory (default: the name)")
+	flagModule = flag.String("module", "", "module path; if set, a go.mod is written too")
+	flagForce  = flag.Bool("f", false, "overwrite existing files")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("winsvc-new: ")
+	flag.Parse()
+	if *flagName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := *flagDir
+	if dir == "" {
+		dir = *flagName
+	}
+	desc := *flagDesc
+	if desc == "" {
+		desc = *flagName
+	}
+
+	manifest, err := json.MarshalIndent(winsvc.ServiceConfig{
+		DisplayName: desc,
+		Description: desc,
+		StartType:   winsvc.StartTypeAutomatic,
+		Recovery: &winsvc.RecoveryConfig{
+			Actions: []winsvc.RecoveryAction{
+				{Type: winsvc.RecoveryRestart, Delay: 5 * time.Second},
+				{Type: winsvc.RecoveryRestart, Delay: 30 * time.Second},
+			},
+			ResetPeriod: 24 * time.Hour,
+		},
+	}, "", "\t")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	files := []struct {
+		name string
+		tmpl *template.Template
+	}{
+		{"main.go", mainTemplate},
+		{"service.json", manifestTemplate},
+	}
+	if *flagModule != "" {
+		files = append(files, struct {
+			name string
+			tmpl *template.Template
+		}{"go.mod", modTemplate})
+	}
+	data := map[string]string{
+		"Name":     *flagName,
+		"Module":   *flagModule,
+		"Manifest": string(manifest),
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if _, err := os.Stat(path); err == nil && !*flagForce {
+			log.Fatalf("%s exists, use -f to overwrite it", path)
+		}
+		out, err := os.Create(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := f.tmpl.Execute(out, data); err != nil {
+			log.Fatal(err)
+		}
+		if err := out.Close(); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(path)
+	}
+	if *flagModule != "" {
+		fmt.Printf("run \"go mod tidy\" in %s to fetch the dependencies\n", dir)
+	}
+}
+
+var mainTemplate = template.Must(template.New("main.go").Parse(`// Command {{.Name}} is a Windows service.
+//
+//	{{.Name}}.exe install|remove|start|stop|status
+//	{{.Name}}.exe           run on the console, or as the service
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/chai2010/winsvc"
+)
+
+const serviceName = "{{.Name}}"
+
+//go:embed service.json
+var manifest []byte
+
+func main() {
+	var cfg winsvc.ServiceConfig
+	if err := json.Unmarshal(manifest, &cfg); err != nil {
+		log.Fatalf("service.json: %v", err)
+	}
+	if handled, err := winsvc.HandleCommandLine(serviceName, cfg, os.Args[1:]); handled {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// On the console, the service runs until Ctrl+C.
+	isDebug := winsvc.IsAnInteractiveSession()
+	err := winsvc.RunAsServiceContext(serviceName, run, isDebug,
+		winsvc.WithStopTimeout(20*time.Second),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run does the work of the service until ctx is canceled, when the
+// service is asked to stop. Return once everything is shut down.
+func run(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// do the work
+		}
+	}
+}
+`))
+
+var manifestTemplate = template.Must(template.New("service.json").Parse(`{{.Manifest}}
+`))
+
+var modTemplate = template.Must(template.New("go.mod").Parse(`module {{.Module}}
+
+go 1.16
+`))
@@ -0,0 +1,94 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Codec encodes and decodes a ServiceConfig in a format such as JSON.
+// ServiceConfig has json, yaml and toml field tags, so the Marshal and
+// Unmarshal functions of most YAML and TOML packages can be registered
+// as they are (see CodecFuncs and RegisterCodec).
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// CodecFuncs turns a pair of Marshal and Unmarshal functions into a
+// Codec, such as CodecFuncs{yaml.Marshal, yaml.Unmarshal}.
+type CodecFuncs struct {
+	MarshalFunc   func(v interface{}) ([]byte, error)
+	UnmarshalFunc func(data []byte, v interface{}) error
+}
+
+func (p CodecFuncs) Marshal(v interface{}) ([]byte, error) {
+	return p.MarshalFunc(v)
+}
+
+func (p CodecFuncs) Unmarshal(data []byte, v interface{}) error {
+	return p.UnmarshalFunc(data, v)
+}
+
+var codecs = struct {
+	sync.Mutex
+	m map[string]Codec
+}{
+	m: map[string]Codec{
+		"json": CodecFuncs{
+			func(v interface{}) ([]byte, error) { return json.MarshalIndent(v, "", "  ") },
+			json.Unmarshal,
+		},
+	},
+}
+
+// RegisterCodec makes c the codec of format, such as "yaml" or "toml".
+// The "json" codec, using encoding/json, is registered by default.
+func RegisterCodec(format string, c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.m[format] = c
+}
+
+func codec(format string) (Codec, error) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	c, ok := codecs.m[format]
+	if !ok {
+		return nil, fmt.Errorf("no codec registered for format %q", format)
+	}
+	return c, nil
+}
+
+// MarshalConfig encodes cfg in format. The fields left to their zero
+// value are omitted. Password is never encoded, so that a configuration
+// file does not hold it in clear text, and UnmarshalConfig does not
+// decode it: set it from a secret store before installing.
+func MarshalConfig(format string, cfg ServiceConfig) ([]byte, error) {
+	c, err := codec(format)
+	if err != nil {
+		return nil, fmt.Errorf("winsvc.MarshalConfig: %v", err)
+	}
+	b, err := c.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("winsvc.MarshalConfig: %v", err)
+	}
+	return b, nil
+}
+
+// UnmarshalConfig decodes a ServiceConfig encoded in format.
+func UnmarshalConfig(format string, data []byte) (ServiceConfig, error) {
+	c, err := codec(format)
+	if err != nil {
+		return ServiceConfig{}, fmt.Errorf("winsvc.UnmarshalConfig: %v", err)
+	}
+	var cfg ServiceConfig
+	if err := c.Unmarshal(data, &cfg); err != nil {
+		return ServiceConfig{}, fmt.Errorf("winsvc.UnmarshalConfig: %v", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	cfg := ServiceConfig{
+		DisplayName:        "My Service",
+		Description:        "does things",
+		StartType:          StartTypeAutomatic,
+		Dependencies:       []string{"Tcpip", "+NetworkProvider"},
+		Account:            `NT AUTHORITY\NetworkService`,
+		SidType:            SidTypeUnrestricted,
+		Args:               []string{"-config", `C:\svc\config.yaml`},
+		PreshutdownTimeout: 30 * time.Second,
+		Recovery: &RecoveryConfig{
+			Actions: []RecoveryAction{{Type: RecoveryRestart, Delay: time.Minute}},
+		},
+		EventLog:    "MyProduct",
+		EventSource: "MyService",
+		Parameters:  map[string]string{"Port": "8080"},
+	}
+	b, err := MarshalConfig("json", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalConfig("json", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cfg) {
+		t.Errorf("round trip gave\n%+v\nwant\n%+v", got, cfg)
+	}
+}
+
+func TestConfigPasswordNotEncoded(t *testing.T) {
+	b, err := MarshalConfig("json", ServiceConfig{Account: `.\svcuser`, Password: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("s3cret")) {
+		t.Errorf("encoded config holds the password: %s", b)
+	}
+	cfg, err := UnmarshalConfig("json", []byte(`{"account": ".\\svcuser", "password": "s3cret"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "" {
+		t.Errorf("decoded password %q, want none", cfg.Password)
+	}
+}
+
+func TestUnknownCodec(t *testing.T) {
+	if _, err := MarshalConfig("xml", ServiceConfig{}); err == nil {
+		t.Error("MarshalConfig with an unregistered format returned nil error")
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+// Compatibility tells whether the package uses the optional Windows
+// features missing from minimal installations, such as Nano Server and
+// Server Core (see Options.Compatibility).
+type Compatibility int
+
+const (
+	CompatAuto    Compatibility = iota // use the features the computer has (default)
+	CompatFull                         // use every feature, failing where one is missing
+	CompatMinimal                      // use none of the optional features
+)
+
+// Capabilities are the optional Windows features the package uses. A
+// missing feature is done without: resource strings are installed
+// unchecked, crash dumps and session helpers are disabled with a
+// warning, and a service which cannot open the event log logs to a file
+// (see DetectCapabilities).
+type Capabilities struct {
+	InstallationType string // such as "Client", "Server", "Server Core" or "Nano Server"
+	EventLog         bool   // the event log can be written
+	EventCreate      bool   // EventCreate.exe, the message file of the default event sources
+	Shell            bool   // shlwapi.dll, which loads the resource strings of DisplayName and Description
+	CrashDumps       bool   // dbghelp.dll, which writes crash dumps (see WithCrashDump)
+	Sessions         bool   // user sessions, for WithSessionHelper
+}
+
+// Minimal reports whether the computer is a minimal installation.
+func (c Capabilities) Minimal() bool {
+	return c.InstallationType == "Nano Server" || c.InstallationType == "Server Core"
+}
@@ -0,0 +1,256 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// StartType tells when the service control manager starts a service.
+type StartType int
+
+const (
+	StartTypeAutomatic        StartType = iota // started at boot
+	StartTypeAutomaticDelayed                  // started shortly after the other automatic services
+	StartTypeManual                            // started on demand
+	StartTypeDisabled                          // cannot be started
+)
+
+func (t StartType) String() string {
+	switch t {
+	case StartTypeAutomatic:
+		return "Automatic"
+	case StartTypeAutomaticDelayed:
+		return "AutomaticDelayed"
+	case StartTypeManual:
+		return "Manual"
+	case StartTypeDisabled:
+		return "Disabled"
+	}
+	return fmt.Sprintf("StartType(%d)", int(t))
+}
+
+// ServiceType is the Windows service type. The values match the
+// SERVICE_WIN32_* flags.
+type ServiceType uint32
+
+const (
+	ServiceTypeOwnProcess   ServiceType = 0x10 // the service runs in its own process (default)
+	ServiceTypeShareProcess ServiceType = 0x20 // the service shares a process with other services
+)
+
+// ErrorControl is the severity of a failure to start the service at boot.
+// The values match the SERVICE_ERROR_* constants.
+type ErrorControl uint32
+
+const (
+	ErrorIgnore   ErrorControl = 0 // log the error and continue
+	ErrorNormal   ErrorControl = 1 // log the error, show a message and continue
+	ErrorSevere   ErrorControl = 2 // restart with the last known good configuration
+	ErrorCritical ErrorControl = 3 // restart with the last known good configuration, or fail the boot
+)
+
+// SidType is the service SID type. The values match the
+// SERVICE_SID_TYPE_* constants.
+type SidType uint32
+
+const (
+	SidTypeNone         SidType = 0
+	SidTypeUnrestricted SidType = 1
+	SidTypeRestricted   SidType = 3
+)
+
+// RecoveryActionType is what the service control manager does when a
+// service fails. The values match the SC_ACTION_* constants.
+type RecoveryActionType int
+
+const (
+	RecoveryNone       RecoveryActionType = 0 // do nothing
+	RecoveryRestart    RecoveryActionType = 1 // restart the service
+	RecoveryReboot     RecoveryActionType = 2 // reboot the computer
+	RecoveryRunCommand RecoveryActionType = 3 // run the recovery command
+)
+
+type RecoveryAction struct {
+	Type  RecoveryActionType `json:"type,omitempty" yaml:"type,omitempty" toml:"type,omitempty"`
+	Delay time.Duration      `json:"delay,omitempty" yaml:"delay,omitempty" toml:"delay,omitempty"` // time to wait before performing the action
+}
+
+// RecoveryConfig are the failure actions of a service. The Nth failure
+// runs Actions[N-1], or the last action once they are used up.
+type RecoveryConfig struct {
+	Actions            []RecoveryAction `json:"actions,omitempty" yaml:"actions,omitempty" toml:"actions,omitempty"`
+	ResetPeriod        time.Duration    `json:"resetPeriod,omitempty" yaml:"resetPeriod,omitempty" toml:"resetPeriod,omitempty"`                      // time without failures after which the failure count is reset
+	RebootMessage      string           `json:"rebootMessage,omitempty" yaml:"rebootMessage,omitempty" toml:"rebootMessage,omitempty"`                // broadcast before a RecoveryReboot
+	Command            string           `json:"command,omitempty" yaml:"command,omitempty" toml:"command,omitempty"`                                  // command line run by RecoveryRunCommand
+	OnNonCrashFailures bool             `json:"onNonCrashFailures,omitempty" yaml:"onNonCrashFailures,omitempty" toml:"onNonCrashFailures,omitempty"` // also act when the service stops with a non-zero exit code
+}
+
+// TriggerType is the kind of event of a service trigger. The values match
+// the SERVICE_TRIGGER_TYPE_* constants.
+type TriggerType uint32
+
+const (
+	TriggerDeviceInterfaceArrival TriggerType = 1
+	TriggerIPAddressAvailability  TriggerType = 2
+	TriggerDomainJoin             TriggerType = 3
+	TriggerFirewallPortEvent      TriggerType = 4
+	TriggerGroupPolicy            TriggerType = 5
+	TriggerNetworkEndpoint        TriggerType = 6
+	TriggerCustom                 TriggerType = 20
+)
+
+// TriggerAction is what a trigger does to the service.
+type TriggerAction uint32
+
+const (
+	TriggerActionStart TriggerAction = 1
+	TriggerActionStop  TriggerAction = 2
+)
+
+// Well known trigger subtypes.
+const (
+	TriggerSubtypeFirstIPAddressArrival = "{4F27F2DE-14E2-430B-A549-7CD48CBC8245}"
+	TriggerSubtypeLastIPAddressRemoval  = "{CC4BA62A-162E-4648-847A-B6BDF993E335}"
+	TriggerSubtypeDomainJoin            = "{1CE20ABA-9851-4421-9430-1DDEB766E809}"
+	TriggerSubtypeDomainLeave           = "{DDAF516E-58C2-4866-9574-C3B615D42EA1}"
+	TriggerSubtypeFirewallPortOpen      = "{B7569E07-8421-4EE0-AD10-86915AFDAD09}"
+	TriggerSubtypeFirewallPortClose     = "{A144ED38-8E12-4DE4-9D96-E64740B1A524}"
+	TriggerSubtypeMachinePolicyPresent  = "{659FCAE6-5BDB-4DA9-B1FF-CA2A178D46E0}"
+	TriggerSubtypeUserPolicyPresent     = "{54FB46C8-F089-464C-B1FD-59D1B62C3B50}"
+)
+
+// TriggerDataType is the type of a trigger data item. The values match
+// the SERVICE_TRIGGER_DATA_TYPE_* constants.
+type TriggerDataType uint32
+
+const (
+	TriggerDataBinary     TriggerDataType = 1
+	TriggerDataString     TriggerDataType = 2
+	TriggerDataLevel      TriggerDataType = 3
+	TriggerDataKeywordAny TriggerDataType = 4
+	TriggerDataKeywordAll TriggerDataType = 5
+)
+
+// TriggerData is a data item of a trigger, kept as the raw bytes Windows
+// stores. String items are UTF-16LE, see StringTriggerData.
+type TriggerData struct {
+	Type TriggerDataType `json:"type,omitempty" yaml:"type,omitempty" toml:"type,omitempty"`
+	Data []byte          `json:"data,omitempty" yaml:"data,omitempty" toml:"data,omitempty"`
+}
+
+// StringTriggerData returns a string data item holding ss.
+func StringTriggerData(ss ...string) TriggerData {
+	var b []byte
+	for _, s := range ss {
+		for _, r := range utf16.Encode([]rune(s)) {
+			b = append(b, byte(r), byte(r>>8))
+		}
+		b = append(b, 0, 0)
+	}
+	return TriggerData{Type: TriggerDataString, Data: b}
+}
+
+// Trigger starts or stops a service when a system event happens.
+type Trigger struct {
+	Type    TriggerType   `json:"type,omitempty" yaml:"type,omitempty" toml:"type,omitempty"`
+	Action  TriggerAction `json:"action,omitempty" yaml:"action,omitempty" toml:"action,omitempty"`
+	Subtype string        `json:"subtype,omitempty" yaml:"subtype,omitempty" toml:"subtype,omitempty"` // GUID of the event, such as TriggerSubtypeFirstIPAddressArrival
+	Data    []TriggerData `json:"data,omitempty" yaml:"data,omitempty" toml:"data,omitempty"`
+}
+
+// ServiceConfig is the configuration a service is installed with.
+// The zero value installs an automatic start service running as
+// LocalSystem in its own process.
+type ServiceConfig struct {
+	// DisplayName and Description are plain text, or resource references
+	// (see ResourceString) for strings localized by services.msc.
+	DisplayName  string       `json:"displayName,omitempty" yaml:"displayName,omitempty" toml:"displayName,omitempty"`
+	Description  string       `json:"description,omitempty" yaml:"description,omitempty" toml:"description,omitempty"`
+	StartType    StartType    `json:"startType,omitempty" yaml:"startType,omitempty" toml:"startType,omitempty"`
+	ServiceType  ServiceType  `json:"serviceType,omitempty" yaml:"serviceType,omitempty" toml:"serviceType,omitempty"` // zero means ServiceTypeOwnProcess
+	Interactive  bool         `json:"interactive,omitempty" yaml:"interactive,omitempty" toml:"interactive,omitempty"` // may interact with the desktop (LocalSystem only, legacy)
+	ErrorControl ErrorControl `json:"errorControl,omitempty" yaml:"errorControl,omitempty" toml:"errorControl,omitempty"`
+
+	// LoadOrderGroup is the load ordering group the service belongs to.
+	// TagId is the tag of the service within that group; it is assigned
+	// by the system and only reported, never installed.
+	LoadOrderGroup string `json:"loadOrderGroup,omitempty" yaml:"loadOrderGroup,omitempty" toml:"loadOrderGroup,omitempty"`
+	TagId          uint32 `json:"tagId,omitempty" yaml:"tagId,omitempty" toml:"tagId,omitempty"`
+
+	Dependencies []string `json:"dependencies,omitempty" yaml:"dependencies,omitempty" toml:"dependencies,omitempty"` // services or groups (prefixed with "+") started first
+	Account      string   `json:"account,omitempty" yaml:"account,omitempty" toml:"account,omitempty"`                // account the service runs as, empty for LocalSystem (see UpdateService)
+	Password     string   `json:"-" yaml:"-" toml:"-"`                                                                // never encoded, see MarshalConfig
+	SidType      SidType  `json:"sidType,omitempty" yaml:"sidType,omitempty" toml:"sidType,omitempty"`
+	Args         []string `json:"args,omitempty" yaml:"args,omitempty" toml:"args,omitempty"` // command line arguments the service binary is started with
+
+	// SkipLogonRight keeps the install functions from granting Account
+	// the "Log on as a service" right (see GrantLogonRight), such as when
+	// a group policy manages it.
+	SkipLogonRight bool `json:"skipLogonRight,omitempty" yaml:"skipLogonRight,omitempty" toml:"skipLogonRight,omitempty"`
+
+	// BinaryPath is the service binary. GetServiceConfig reports it; the
+	// install functions use their appPath argument unless it is empty.
+	BinaryPath string `json:"binaryPath,omitempty" yaml:"binaryPath,omitempty" toml:"binaryPath,omitempty"`
+
+	Recovery *RecoveryConfig `json:"recovery,omitempty" yaml:"recovery,omitempty" toml:"recovery,omitempty"` // nil for no failure actions
+	Triggers []Trigger       `json:"triggers,omitempty" yaml:"triggers,omitempty" toml:"triggers,omitempty"`
+
+	// PreshutdownTimeout is how long the service control manager waits
+	// at system shutdown for a service accepting PreShutdown (see
+	// AcceptPreShutdown) to stop, before going on with the other
+	// services; zero keeps the system default. The order in which those
+	// services are notified is set by SetPreshutdownOrder.
+	PreshutdownTimeout time.Duration `json:"preshutdownTimeout,omitempty" yaml:"preshutdownTimeout,omitempty" toml:"preshutdownTimeout,omitempty"`
+
+	// EventLog and EventSource are the event log the service logs to and
+	// its source name there; empty means the Application log and the
+	// service name. A custom source may be shared by several services:
+	// removing a service unregisters the source its install created only
+	// once no other installed service logs as it.
+	// GetServiceConfig does not report them.
+	EventLog    string `json:"eventLog,omitempty" yaml:"eventLog,omitempty" toml:"eventLog,omitempty"`
+	EventSource string `json:"eventSource,omitempty" yaml:"eventSource,omitempty" toml:"eventSource,omitempty"`
+
+	// SourceConflict is what to do if EventSource is already registered.
+	SourceConflict SourceConflict `json:"sourceConflict,omitempty" yaml:"sourceConflict,omitempty" toml:"sourceConflict,omitempty"`
+
+	// EventLogConfig, if not nil, sets the size and retention of EventLog
+	// (see ConfigureEventLog). It is ignored for the Application log.
+	EventLogConfig *EventLogConfig `json:"eventLogConfig,omitempty" yaml:"eventLogConfig,omitempty" toml:"eventLogConfig,omitempty"`
+
+	// Security is the discretionary ACL of the service in SDDL (see
+	// SetServiceSecurity), empty for the default one, such as to let an
+	// operator group start and stop the service. GetServiceConfig does
+	// not report it.
+	Security string `json:"security,omitempty" yaml:"security,omitempty" toml:"security,omitempty"`
+
+	// Parameters are stored under the Parameters registry key of the
+	// service (see SetParameters), keeping the values already there.
+	// GetServiceConfig does not report them.
+	Parameters map[string]string `json:"parameters,omitempty" yaml:"parameters,omitempty" toml:"parameters,omitempty"`
+
+	// Vars are the values of the {{.Name}} placeholders of Args and
+	// Parameters, expanded when the service is installed or updated.
+	// The built-in ServiceName, AppPath, AppDir, DataDir (the service
+	// directory in ProgramData) and ComputerName can be overridden.
+	// %NAME% environment variables are expanded as the service starts
+	// (see ExpandParameters).
+	Vars map[string]string `json:"vars,omitempty" yaml:"vars,omitempty" toml:"vars,omitempty"`
+}
+
+// ResourceString returns a reference to string resource id of the module
+// dll, in the "@dll,-id" form accepted for DisplayName and Description.
+// dll may contain environment variables such as %SystemRoot%.
+func ResourceString(dll string, id uint32) string {
+	return fmt.Sprintf("@%s,-%d", dll, id)
+}
+
+func isResourceString(s string) bool {
+	return strings.HasPrefix(s, "@")
+}
@@ -0,0 +1,184 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// SaveConfigToRegistry stores cfg as values of the HKLM registry key
+// path, such as `SOFTWARE\Vendor\Product\Services\name`, creating the key
+// if needed, so that deployment tooling can keep the declarative
+// configuration of a service alongside its own. Each field takes the
+// value named by its json tag: strings are REG_SZ, string lists
+// REG_MULTI_SZ, durations REG_QWORD, the other numbers and booleans
+// REG_DWORD, and the others, such as
+// Recovery and Triggers, REG_SZ holding their JSON encoding. The values
+// of the zero fields are removed. Password is never stored.
+func SaveConfigToRegistry(path string, cfg ServiceConfig) error {
+	return saveConfig("", path, cfg)
+}
+
+// LoadConfigFromRegistry returns the configuration stored by
+// SaveConfigToRegistry under the HKLM registry key path.
+func LoadConfigFromRegistry(path string) (ServiceConfig, error) {
+	return loadConfig("", path)
+}
+
+func (p *Manager) SaveConfigToRegistry(path string, cfg ServiceConfig) error {
+	return saveConfig(p.host, path, cfg)
+}
+
+func (p *Manager) LoadConfigFromRegistry(path string) (ServiceConfig, error) {
+	return loadConfig(p.host, path)
+}
+
+// configFields calls fn with the registry value name and the value of
+// each stored field of cfg.
+func configFields(cfg reflect.Value, fn func(name string, v reflect.Value) error) error {
+	t := cfg.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Name == "Password" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		if err := fn(name, cfg.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveConfig(host, path string, cfg ServiceConfig) error {
+	hklm, err := openLocalMachine(host)
+	if err != nil {
+		return fmt.Errorf("winsvc.SaveConfigToRegistry: %v", err)
+	}
+	defer closeLocalMachine(hklm)
+	k, _, err := registry.CreateKey(hklm, path, registry.SET_VALUE|keyView)
+	if err != nil {
+		return fmt.Errorf("winsvc.SaveConfigToRegistry: could not create %s: %v", path, err)
+	}
+	defer k.Close()
+	err = configFields(reflect.ValueOf(cfg), func(name string, v reflect.Value) error {
+		if v.IsZero() {
+			if err := k.DeleteValue(name); err != nil && err != registry.ErrNotExist {
+				return err
+			}
+			return nil
+		}
+		switch v.Kind() {
+		case reflect.String:
+			return k.SetStringValue(name, v.String())
+		case reflect.Bool:
+			return k.SetDWordValue(name, 1)
+		case reflect.Int, reflect.Int32:
+			return k.SetDWordValue(name, uint32(v.Int()))
+		case reflect.Int64:
+			return k.SetQWordValue(name, uint64(v.Int()))
+		case reflect.Uint32:
+			return k.SetDWordValue(name, uint32(v.Uint()))
+		case reflect.Slice:
+			if ss, ok := v.Interface().([]string); ok {
+				return k.SetStringsValue(name, ss)
+			}
+		}
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return err
+		}
+		return k.SetStringValue(name, string(b))
+	})
+	if err != nil {
+		return fmt.Errorf("winsvc.SaveConfigToRegistry: %v", err)
+	}
+	return nil
+}
+
+func loadConfig(host, path string) (ServiceConfig, error) {
+	var cfg ServiceConfig
+	hklm, err := openLocalMachine(host)
+	if err != nil {
+		return cfg, fmt.Errorf("winsvc.LoadConfigFromRegistry: %v", err)
+	}
+	defer closeLocalMachine(hklm)
+	k, err := registry.OpenKey(hklm, path, registry.QUERY_VALUE|keyView)
+	if err != nil {
+		return cfg, fmt.Errorf("winsvc.LoadConfigFromRegistry: could not open %s: %v", path, err)
+	}
+	defer k.Close()
+	err = configFields(reflect.ValueOf(&cfg).Elem(), func(name string, v reflect.Value) error {
+		switch v.Kind() {
+		case reflect.String:
+			s, _, err := k.GetStringValue(name)
+			if err == nil {
+				v.SetString(s)
+			}
+			return ignoreNotExist(err)
+		case reflect.Bool:
+			n, _, err := k.GetIntegerValue(name)
+			if err == nil {
+				v.SetBool(n != 0)
+			}
+			return ignoreNotExist(err)
+		case reflect.Int, reflect.Int32:
+			n, _, err := k.GetIntegerValue(name)
+			if err == nil {
+				v.SetInt(int64(int32(n)))
+			}
+			return ignoreNotExist(err)
+		case reflect.Int64:
+			n, _, err := k.GetIntegerValue(name)
+			if err == nil {
+				v.SetInt(int64(n))
+			}
+			return ignoreNotExist(err)
+		case reflect.Uint32:
+			n, _, err := k.GetIntegerValue(name)
+			if err == nil {
+				v.SetUint(n)
+			}
+			return ignoreNotExist(err)
+		case reflect.Slice:
+			if v.Type() == reflect.TypeOf([]string(nil)) {
+				ss, _, err := k.GetStringsValue(name)
+				if err == nil {
+					v.Set(reflect.ValueOf(ss))
+				}
+				return ignoreNotExist(err)
+			}
+		}
+		s, _, err := k.GetStringValue(name)
+		if err != nil {
+			return ignoreNotExist(err)
+		}
+		if err := json.Unmarshal([]byte(s), v.Addr().Interface()); err != nil {
+			return fmt.Errorf("could not decode %s: %v", name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return ServiceConfig{}, fmt.Errorf("winsvc.LoadConfigFromRegistry: %v", err)
+	}
+	return cfg, nil
+}
+
+func ignoreNotExist(err error) error {
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	return err
+}
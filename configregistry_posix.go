@@ -0,0 +1,20 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+func SaveConfigToRegistry(path string, cfg ServiceConfig) error {
+	panic("winsvc: only support windows!")
+}
+func LoadConfigFromRegistry(path string) (ServiceConfig, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SaveConfigToRegistry(path string, cfg ServiceConfig) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) LoadConfigFromRegistry(path string) (ServiceConfig, error) {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,93 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	moddbghelp            = windows.NewLazySystemDLL("dbghelp.dll")
+	procMiniDumpWriteDump = moddbghelp.NewProc("MiniDumpWriteDump")
+)
+
+const (
+	miniDumpWithDataSegs   = 0x00000001
+	miniDumpWithHandleData = 0x00000004
+	miniDumpWithThreadInfo = 0x00001000
+)
+
+// WriteCrashDump writes a minidump of the current process to path.
+func WriteCrashDump(path string) error {
+	if err := procMiniDumpWriteDump.Find(); err != nil {
+		return fmt.Errorf("winsvc.WriteCrashDump: %v", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r1, _, e1 := procMiniDumpWriteDump.Call(
+		uintptr(windows.CurrentProcess()),
+		uintptr(windows.GetCurrentProcessId()),
+		f.Fd(),
+		miniDumpWithDataSegs|miniDumpWithHandleData|miniDumpWithThreadInfo,
+		0, 0, 0,
+	)
+	if r1 == 0 {
+		return fmt.Errorf("winsvc.WriteCrashDump: MiniDumpWriteDump failed: %v", e1)
+	}
+	return nil
+}
+
+// crashDumpPath returns where the dump for a crash happening now goes.
+func (p *serviceRuntime) crashDumpPath() (string, error) {
+	dir := p.opts.crashDumpDir
+	if dir == "" {
+		appPath, err := GetAppPath()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Dir(appPath)
+	}
+	name := fmt.Sprintf("%s-%s-%d.dmp", p.name, time.Now().Format("20060102-150405"), os.Getpid())
+	return filepath.Join(dir, name), nil
+}
+
+// callStart calls the start function. If crash dumps are enabled, a panic
+// is logged and dumped before it is allowed to crash the process.
+func (p *serviceRuntime) callStart(ctx context.Context, status StatusReporter) {
+	if p.opts.crashDump {
+		defer func() {
+			if r := recover(); r != nil {
+				p.elog.Error(1, fmt.Sprintf("winsvc.Execute: panic: %v\n%s", r, debug.Stack()))
+				p.writeCrashDump()
+				panic(r)
+			}
+		}()
+	}
+	p.start(ctx, status)
+}
+
+func (p *serviceRuntime) writeCrashDump() {
+	path, err := p.crashDumpPath()
+	if err == nil {
+		err = WriteCrashDump(path)
+	}
+	if err != nil {
+		p.elog.Error(1, fmt.Sprintf("winsvc.Execute: could not write crash dump: %v", err))
+		return
+	}
+	p.elog.Error(1, fmt.Sprintf("winsvc.Execute: crash dump written to %s", path))
+}
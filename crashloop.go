@@ -0,0 +1,58 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CrashLoopExitCode is the service specific exit code of a service which
+// refused to start because it is crash looping (see
+// WithCrashLoopBreaker).
+const CrashLoopExitCode = 3
+
+// CrashLoopPolicy tells when a service is crash looping: when it stopped
+// uncleanly MaxFailures times within Window, without a clean stop since.
+// Window should be longer than the recovery action delays of the
+// service, so that the restarts of the service control manager are
+// counted together. The stats keep the last 10 unclean stops only, a
+// larger MaxFailures counts as 10.
+type CrashLoopPolicy struct {
+	MaxFailures int
+	Window      time.Duration
+}
+
+// failures returns the failures of recent within p.Window before now,
+// if they trip the breaker.
+func (p CrashLoopPolicy) failures(recent []StopRecord, now time.Time) []StopRecord {
+	if p.MaxFailures <= 0 {
+		return nil
+	}
+	if p.MaxFailures > maxRecentFailures {
+		p.MaxFailures = maxRecentFailures
+	}
+	var in []StopRecord
+	for _, f := range recent {
+		if now.Sub(f.Time) <= p.Window {
+			in = append(in, f)
+		}
+	}
+	if len(in) < p.MaxFailures {
+		return nil
+	}
+	return in
+}
+
+// crashLoopMessage is the event logged when the breaker trips.
+func crashLoopMessage(p CrashLoopPolicy, failures []StopRecord) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "crash loop detected: %d unclean stops in %v, not starting", len(failures), p.Window)
+	for _, f := range failures {
+		fmt.Fprintf(&b, "\n%s: %s, exit code %d/%d", f.Time.Format(time.RFC3339), f.Reason, f.Win32ExitCode, f.ServiceSpecificExitCode)
+	}
+	return b.String()
+}
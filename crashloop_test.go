@@ -0,0 +1,45 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCrashLoopFailures(t *testing.T) {
+	now := time.Now()
+	// the stats as addFailure leaves them after n unclean stops, a
+	// minute apart
+	stats := func(n int) []StopRecord {
+		var s ServiceStats
+		for i := n; i > 0; i-- {
+			s.addFailure(StopRecord{Time: now.Add(-time.Duration(i) * time.Minute), Reason: "Exited"})
+		}
+		return s.RecentFailures
+	}
+	for _, tt := range []struct {
+		max, stops int
+		window     time.Duration
+		want       int // failures returned, zero if the breaker holds
+	}{
+		{0, 5, time.Hour, 0},
+		{3, 2, time.Hour, 0},
+		{3, 3, time.Hour, 3},
+		{3, 5, 2 * time.Minute, 0}, // 2 stops in the window
+		{3, 5, 3 * time.Minute, 3},
+		{maxRecentFailures, maxRecentFailures - 1, time.Hour, 0},
+		{maxRecentFailures, maxRecentFailures, time.Hour, maxRecentFailures},
+		{maxRecentFailures, 20, time.Hour, maxRecentFailures},
+		{maxRecentFailures + 1, maxRecentFailures, time.Hour, maxRecentFailures},
+		{50, 50, time.Hour, maxRecentFailures},
+		{50, maxRecentFailures - 1, time.Hour, 0},
+	} {
+		p := CrashLoopPolicy{MaxFailures: tt.max, Window: tt.window}
+		if got := len(p.failures(stats(tt.stops), now)); got != tt.want {
+			t.Errorf("MaxFailures %d, %d stops in %v: %d failures, want %d", tt.max, tt.stops, tt.window, got, tt.want)
+		}
+	}
+}
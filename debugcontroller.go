@@ -0,0 +1,140 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+// errDebugStopped is returned when a control is sent to a service under
+// a DebugController which has stopped.
+var errDebugStopped = errors.New("winsvc: debug service is not running")
+
+// DebugController stands in for the service control manager of a service
+// running with WithDebugController, such as in a test: it passes start
+// arguments to the service, sends it controls and tracks its state.
+type DebugController struct {
+	args    []string
+	cmds    chan svc.ChangeRequest
+	changes chan svc.Status
+	done    chan struct{}
+
+	mu      sync.Mutex
+	status  svc.Status
+	changed chan struct{} // closed when status changes
+}
+
+// NewDebugController returns a controller starting the service with the
+// start arguments args (see ServiceArgs). A controller runs the service
+// once.
+func NewDebugController(args ...string) *DebugController {
+	return &DebugController{
+		args:    args,
+		cmds:    make(chan svc.ChangeRequest),
+		changes: make(chan svc.Status),
+		done:    make(chan struct{}),
+		status:  svc.Status{State: svc.Stopped},
+		changed: make(chan struct{}),
+	}
+}
+
+// run runs h as debug.Run does, with the controls sent through c.
+func (c *DebugController) run(name string, h svc.Handler) error {
+	go func() {
+		for {
+			select {
+			case s := <-c.changes:
+				c.setStatus(s)
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	_, errno := h.Execute(append([]string{name}, c.args...), c.cmds, c.changes)
+	close(c.done)
+	c.setStatus(svc.Status{State: svc.Stopped, Win32ExitCode: errno})
+	if errno != 0 {
+		return windows.Errno(errno)
+	}
+	return nil
+}
+
+func (c *DebugController) setStatus(s svc.Status) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = s
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// State returns the state last reported by the service, such as
+// "Running", or "Stopped" before it starts and once it stopped.
+func (c *DebugController) State() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return stateString(c.status.State)
+}
+
+// Done returns a channel which is closed once the service has stopped.
+func (c *DebugController) Done() <-chan struct{} {
+	return c.done
+}
+
+// Control sends control code cmd to the service, such as ControlReload,
+// waiting until the service takes it.
+func (c *DebugController) Control(cmd uint32) error {
+	c.mu.Lock()
+	current := c.status
+	c.mu.Unlock()
+	select {
+	case c.cmds <- svc.ChangeRequest{Cmd: svc.Cmd(cmd), CurrentStatus: current}:
+		return nil
+	case <-c.done:
+		return errDebugStopped
+	}
+}
+
+func (c *DebugController) Stop() error {
+	return c.Control(uint32(svc.Stop))
+}
+
+func (c *DebugController) Pause() error {
+	return c.Control(uint32(svc.Pause))
+}
+
+func (c *DebugController) Continue() error {
+	return c.Control(uint32(svc.Continue))
+}
+
+func (c *DebugController) Interrogate() error {
+	return c.Control(uint32(svc.Interrogate))
+}
+
+// WaitState waits until the service reports state, such as "Running",
+// for at most timeout.
+func (c *DebugController) WaitState(state string, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		c.mu.Lock()
+		current, changed := stateString(c.status.State), c.changed
+		c.mu.Unlock()
+		if current == state {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return fmt.Errorf("winsvc: debug service is %s after %v, not %s", current, timeout, state)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+import (
+	"time"
+)
+
+type DebugController struct{}
+
+func NewDebugController(args ...string) *DebugController {
+	panic("winsvc: only support windows!")
+}
+func (c *DebugController) State() string {
+	panic("winsvc: only support windows!")
+}
+func (c *DebugController) Done() <-chan struct{} {
+	panic("winsvc: only support windows!")
+}
+func (c *DebugController) Control(cmd uint32) error {
+	panic("winsvc: only support windows!")
+}
+func (c *DebugController) Stop() error {
+	panic("winsvc: only support windows!")
+}
+func (c *DebugController) Pause() error {
+	panic("winsvc: only support windows!")
+}
+func (c *DebugController) Continue() error {
+	panic("winsvc: only support windows!")
+}
+func (c *DebugController) Interrogate() error {
+	panic("winsvc: only support windows!")
+}
+func (c *DebugController) WaitState(state string, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,82 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"sync"
+	"time"
+)
+
+// Options are the package defaults used by the top-level functions and
+// by Managers. Zero fields keep their built-in default.
+type Options struct {
+	Timeout      time.Duration // how long StopService waits for the service to stop (10s)
+	PollInterval time.Duration // how often service status is polled while waiting (300ms)
+	Logger       Logger        // where services log when WithLogger is not given (the event log)
+	InstallLock  time.Duration // if not zero, how long Managers wait for the install lock (see SetInstallLock)
+
+	// LogRateWindow and LogRateMax, if LogRateWindow is not zero, are
+	// the log rate limit of the services without WithLogRateLimit.
+	LogRateWindow time.Duration
+	LogRateMax    int
+
+	// Compatibility tells whether to use the features missing from
+	// minimal installations, such as Nano Server (see Capabilities).
+	Compatibility Compatibility
+}
+
+// builtinDefaults are the package defaults until SetDefaults.
+var builtinDefaults = Options{
+	Timeout:      10 * time.Second,
+	PollInterval: 300 * time.Millisecond,
+}
+
+var defaults = struct {
+	sync.Mutex
+	Options
+}{
+	Options: builtinDefaults,
+}
+
+// SetDefaults replaces the package defaults by the non-zero fields of o.
+// Use ResetDefaults to clear a field set before.
+func SetDefaults(o Options) {
+	defaults.Lock()
+	defer defaults.Unlock()
+	if o.Timeout > 0 {
+		defaults.Timeout = o.Timeout
+	}
+	if o.PollInterval > 0 {
+		defaults.PollInterval = o.PollInterval
+	}
+	if o.Logger != nil {
+		defaults.Logger = o.Logger
+	}
+	if o.InstallLock > 0 {
+		defaults.InstallLock = o.InstallLock
+	}
+	if o.LogRateWindow > 0 {
+		defaults.LogRateWindow = o.LogRateWindow
+		defaults.LogRateMax = o.LogRateMax
+	}
+	if o.Compatibility != CompatAuto {
+		defaults.Compatibility = o.Compatibility
+	}
+}
+
+// ResetDefaults restores the built-in package defaults, clearing the
+// fields set by SetDefaults.
+func ResetDefaults() {
+	defaults.Lock()
+	defer defaults.Unlock()
+	defaults.Options = builtinDefaults
+}
+
+// Defaults returns the package defaults.
+func Defaults() Options {
+	defaults.Lock()
+	defer defaults.Unlock()
+	return defaults.Options
+}
@@ -0,0 +1,36 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestResetDefaults(t *testing.T) {
+	defer ResetDefaults()
+	SetDefaults(Options{
+		Timeout:       time.Minute,
+		Logger:        NewWriterLogger(ioutil.Discard),
+		InstallLock:   time.Second,
+		LogRateWindow: time.Second,
+		LogRateMax:    5,
+		Compatibility: CompatFull,
+	})
+	// zero fields keep what was set
+	SetDefaults(Options{})
+	if d := Defaults(); d.Logger == nil || d.InstallLock != time.Second || d.Timeout != time.Minute {
+		t.Fatalf("SetDefaults with zero fields changed the defaults to %+v", d)
+	}
+	ResetDefaults()
+	d := Defaults()
+	if d != builtinDefaults {
+		t.Errorf("after ResetDefaults the defaults are %+v, want %+v", d, builtinDefaults)
+	}
+	if d.Logger != nil || d.InstallLock != 0 || d.LogRateWindow != 0 || d.Compatibility != CompatAuto {
+		t.Errorf("ResetDefaults left %+v set", d)
+	}
+}
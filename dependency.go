@@ -0,0 +1,51 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type statusKey struct{}
+
+// WaitForDependency waits until service name is in state, such as
+// "Running", or until ctx is done. Called from the start function of
+// RunAsServiceWithStatus before it reports Running, it reports
+// StartPending checkpoints while waiting, so that a service can wait for
+// a database or a message broker to start without the service control
+// manager giving up on it.
+func WaitForDependency(ctx context.Context, name, state string) error {
+	status, _ := ctx.Value(statusKey{}).(StatusReporter)
+	interval := Defaults().PollInterval
+	for {
+		s, err := QueryService(name)
+		if err != nil {
+			return fmt.Errorf("winsvc.WaitForDependency: %v", err)
+		}
+		if s == state {
+			return nil
+		}
+		if status != nil {
+			status.Pending(interval + 3*time.Second)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("winsvc.WaitForDependency: %s is %s, not %s: %v", name, s, state, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// WaitForDependencies calls WaitForDependency for each of names in turn.
+func WaitForDependencies(ctx context.Context, state string, names ...string) error {
+	for _, name := range names {
+		if err := WaitForDependency(ctx, name, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
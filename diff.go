@@ -0,0 +1,111 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Change is a difference between the desired and the live configuration
+// of a service, as reported by DiffConfig.
+type Change struct {
+	Field   string // name of the ServiceConfig field
+	Current string
+	Desired string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Current, c.Desired)
+}
+
+// diffConfig compares desired with the current configuration. Password
+// and TagId cannot be compared, and an empty desired BinaryPath matches
+// any binary, as a zero desired PreshutdownTimeout matches any timeout.
+func diffConfig(current, desired ServiceConfig) []Change {
+	var changes []Change
+	add := func(field string, cur, want interface{}) {
+		if !reflect.DeepEqual(cur, want) {
+			changes = append(changes, Change{
+				Field:   field,
+				Current: formatConfigValue(cur),
+				Desired: formatConfigValue(want),
+			})
+		}
+	}
+	add("DisplayName", current.DisplayName, desired.DisplayName)
+	add("Description", current.Description, desired.Description)
+	add("StartType", current.StartType, desired.StartType)
+	add("ServiceType", normalizeServiceType(current.ServiceType), normalizeServiceType(desired.ServiceType))
+	add("Interactive", current.Interactive, desired.Interactive)
+	add("ErrorControl", current.ErrorControl, desired.ErrorControl)
+	add("LoadOrderGroup", current.LoadOrderGroup, desired.LoadOrderGroup)
+	add("Dependencies", nonEmpty(current.Dependencies), nonEmpty(desired.Dependencies))
+	if cur, want := normalizeAccount(current.Account), normalizeAccount(desired.Account); !strings.EqualFold(cur, want) {
+		add("Account", cur, want)
+	}
+	add("SidType", current.SidType, desired.SidType)
+	add("Args", nonEmpty(current.Args), nonEmpty(desired.Args))
+	if desired.BinaryPath != "" && !strings.EqualFold(current.BinaryPath, desired.BinaryPath) {
+		add("BinaryPath", current.BinaryPath, desired.BinaryPath)
+	}
+	add("Recovery", normalizeRecovery(current.Recovery), normalizeRecovery(desired.Recovery))
+	add("Triggers", normalizeTriggers(current.Triggers), normalizeTriggers(desired.Triggers))
+	if desired.PreshutdownTimeout != 0 {
+		add("PreshutdownTimeout", current.PreshutdownTimeout, desired.PreshutdownTimeout)
+	}
+	return changes
+}
+
+func formatConfigValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case *RecoveryConfig:
+		if v == nil {
+			return "none"
+		}
+		return fmt.Sprintf("%+v", *v)
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+func normalizeServiceType(t ServiceType) ServiceType {
+	if t == 0 {
+		return ServiceTypeOwnProcess
+	}
+	return t
+}
+
+func normalizeRecovery(rc *RecoveryConfig) *RecoveryConfig {
+	if rc == nil || len(rc.Actions) == 0 {
+		return nil
+	}
+	return rc
+}
+
+func nonEmpty(ss []string) []string {
+	if len(ss) == 0 {
+		return nil
+	}
+	return ss
+}
+
+// normalizeTriggers upper cases the subtype GUIDs, as Windows reports them.
+func normalizeTriggers(ts []Trigger) []Trigger {
+	if len(ts) == 0 {
+		return nil
+	}
+	r := make([]Trigger, len(ts))
+	for i, t := range ts {
+		t.Subtype = strings.ToUpper(t.Subtype)
+		if len(t.Data) == 0 {
+			t.Data = nil
+		}
+		r[i] = t
+	}
+	return r
+}
@@ -0,0 +1,90 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name             string
+		current, desired ServiceConfig
+		want             []string // changed fields
+	}{
+		{
+			name:    "same",
+			current: ServiceConfig{DisplayName: "svc", StartType: StartTypeAutomatic},
+			desired: ServiceConfig{DisplayName: "svc", StartType: StartTypeAutomatic},
+		},
+		{
+			name:    "empty account is LocalSystem",
+			current: ServiceConfig{Account: "LocalSystem"},
+			desired: ServiceConfig{},
+		},
+		{
+			name:    "system spellings",
+			current: ServiceConfig{Account: `NT AUTHORITY\SYSTEM`},
+			desired: ServiceConfig{Account: `.\LocalSystem`},
+		},
+		{
+			name:    "local service spellings",
+			current: ServiceConfig{Account: `NT AUTHORITY\LocalService`},
+			desired: ServiceConfig{Account: "LocalService"},
+		},
+		{
+			name:    "local service with a space",
+			current: ServiceConfig{Account: `NT AUTHORITY\Local Service`},
+			desired: ServiceConfig{Account: AccountLocalService},
+		},
+		{
+			name:    "network service case",
+			current: ServiceConfig{Account: `nt authority\networkservice`},
+			desired: ServiceConfig{Account: "NetworkService"},
+		},
+		{
+			name:    "user account case",
+			current: ServiceConfig{Account: `CONTOSO\svc-web`},
+			desired: ServiceConfig{Account: `contoso\SVC-WEB`},
+		},
+		{
+			name:    "account changed",
+			current: ServiceConfig{Account: "LocalService"},
+			desired: ServiceConfig{Account: "NetworkService"},
+			want:    []string{"Account"},
+		},
+		{
+			name:    "empty lists match nil",
+			current: ServiceConfig{Dependencies: []string{}, Args: []string{}},
+			desired: ServiceConfig{},
+		},
+		{
+			name:    "empty binary path and zero timeout match any",
+			current: ServiceConfig{BinaryPath: `C:\svc.exe`, PreshutdownTimeout: time.Minute},
+			desired: ServiceConfig{},
+		},
+		{
+			name:    "binary path case",
+			current: ServiceConfig{BinaryPath: `C:\SVC.EXE`},
+			desired: ServiceConfig{BinaryPath: `c:\svc.exe`},
+		},
+		{
+			name:    "several changes",
+			current: ServiceConfig{DisplayName: "old", StartType: StartTypeManual, Args: []string{"-a"}},
+			desired: ServiceConfig{DisplayName: "new", StartType: StartTypeAutomatic, Args: []string{"-b"}},
+			want:    []string{"DisplayName", "StartType", "Args"},
+		},
+	} {
+		var got []string
+		for _, c := range diffConfig(tt.current, tt.desired) {
+			got = append(got, c.Field)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: changed %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
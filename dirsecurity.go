@@ -0,0 +1,129 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/windows"
+)
+
+// The rights on the directories of a service, inherited by their files.
+const (
+	dirModify = windows.FILE_GENERIC_READ | windows.FILE_GENERIC_WRITE | windows.FILE_GENERIC_EXECUTE | windows.DELETE
+	dirRead   = windows.FILE_GENERIC_READ | windows.FILE_GENERIC_EXECUTE
+)
+
+func PrepareServiceDirs(name string, dirs ServiceDirs) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.PrepareServiceDirs(name, dirs)
+}
+
+// PrepareServiceDirs creates the directories of service name, and lets
+// the account of the service use them: modify Data and Log, read Config,
+// along with the files they hold. For a service with a service SID (see
+// SidType), the SID is granted the same. Nothing is granted to
+// LocalSystem, which has full access already. Directories are local, so
+// it fails for a Manager of a remote computer.
+func (p *Manager) PrepareServiceDirs(name string, dirs ServiceDirs) error {
+	if p.host != "" {
+		return errors.New("winsvc.PrepareServiceDirs: directories can only be prepared locally")
+	}
+	cfg, err := p.GetServiceConfig(name)
+	if err != nil {
+		return err
+	}
+	accounts := accessAccounts(name, cfg)
+	if dirs.Data == "" {
+		dirs.Data = filepath.Join(os.Getenv("ProgramData"), name)
+	}
+	for _, d := range []struct {
+		path   string
+		rights windows.ACCESS_MASK
+	}{
+		{dirs.Data, dirModify},
+		{dirs.Log, dirModify},
+		{dirs.Config, dirRead},
+	} {
+		if d.path == "" {
+			continue
+		}
+		if err := os.MkdirAll(d.path, 0755); err != nil {
+			return fmt.Errorf("winsvc.PrepareServiceDirs: %v", err)
+		}
+		for _, account := range accounts {
+			if err := grantDirAccess(d.path, account, d.rights); err != nil {
+				return fmt.Errorf("winsvc.PrepareServiceDirs: %s: %v", d.path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// needsDirAccess reports whether account must be granted access to the
+// directories of a service: LocalSystem has full access already.
+func needsDirAccess(account string) bool {
+	return normalizeAccount(account) != AccountLocalSystem
+}
+
+// accessAccounts returns the accounts service name configured as cfg
+// runs as which must be granted access to what the service writes: its
+// account unless LocalSystem, and its service SID if it has one.
+func accessAccounts(name string, cfg ServiceConfig) []string {
+	var accounts []string
+	if needsDirAccess(cfg.Account) {
+		accounts = append(accounts, cfg.Account)
+	}
+	if cfg.SidType != SidTypeNone {
+		accounts = append(accounts, `NT SERVICE\`+name)
+	}
+	return accounts
+}
+
+// grantDirAccess merges an entry granting account rights into the
+// discretionary ACL of directory path, inherited by what it holds.
+func grantDirAccess(path, account string, rights windows.ACCESS_MASK) error {
+	sid, err := lookupAccountSID("", account)
+	if err != nil {
+		return err
+	}
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return err
+	}
+	current, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	dacl, err := grantEntry(current, sid, rights)
+	if err != nil {
+		return err
+	}
+	return windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION, nil, nil, dacl, nil)
+}
+
+// grantEntry merges an entry granting sid rights, inherited by the
+// children of the object, into current.
+func grantEntry(current *windows.ACL, sid *windows.SID, rights windows.ACCESS_MASK) (*windows.ACL, error) {
+	return windows.ACLFromEntries([]windows.EXPLICIT_ACCESS{{
+		AccessPermissions: rights,
+		AccessMode:        windows.GRANT_ACCESS,
+		Inheritance:       windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT,
+		Trustee: windows.TRUSTEE{
+			TrusteeForm:  windows.TRUSTEE_IS_SID,
+			TrusteeType:  windows.TRUSTEE_IS_UNKNOWN,
+			TrusteeValue: windows.TrusteeValueFromSID(sid),
+		},
+	}}, current)
+}
@@ -0,0 +1,70 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+func DrainService(name string, timeout time.Duration) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.Drain(name, timeout)
+}
+
+// Drain asks service name to stop taking new work by sending it the
+// ControlDrain control (see WithDrain), and waits up to timeout for the
+// work in flight to be done, when the service reports Paused.
+func (p *Manager) Drain(name string, timeout time.Duration) error {
+	s, err := p.openService(name, windows.SERVICE_USER_DEFINED_CONTROL|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return fmt.Errorf("winsvc.DrainService: could not access service: %v", err)
+	}
+	defer s.Close()
+	err = p.retry(func() error {
+		_, err := s.Control(svc.Cmd(ControlDrain))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("winsvc.DrainService: could not send drain control: %v", err)
+	}
+	status, err := waitState(s, svc.Paused, timeout)
+	if err != nil {
+		return fmt.Errorf("winsvc.DrainService: %v", err)
+	}
+	if status.State != svc.Paused {
+		return fmt.Errorf("winsvc.DrainService: service %s is %s", name, stateString(status.State))
+	}
+	return nil
+}
+
+// requestDrain asks Execute to drain the service, once.
+func (p *serviceRuntime) requestDrain() {
+	p.drainOnce.Do(func() {
+		close(p.drainRequest)
+	})
+}
+
+// drain reports PausePending and runs the drain callback in the
+// background, closing the returned channel once it returns.
+func (p *serviceRuntime) drain(accepts svc.Accepted) chan struct{} {
+	p.report(svc.Status{State: svc.PausePending, Accepts: accepts, WaitHint: waitHint(p.opts.stopWaitHint)})
+	p.elog.Info(1, "winsvc.Execute: draining")
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		p.opts.drain()
+	}()
+	return drained
+}
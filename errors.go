@@ -0,0 +1,58 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"time"
+)
+
+// errorServiceSpecificError is ERROR_SERVICE_SPECIFIC_ERROR, the Win32 exit
+// code telling that the service specific exit code holds the real one.
+const errorServiceSpecificError = 1066
+
+// ExitError reports that a service stopped with a non-zero exit code.
+type ExitError struct {
+	Name                    string
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+}
+
+func (e *ExitError) Error() string {
+	if e.Win32ExitCode == errorServiceSpecificError {
+		return fmt.Sprintf("winsvc: service %s stopped with service specific exit code %d", e.Name, e.ServiceSpecificExitCode)
+	}
+	return fmt.Sprintf("winsvc: service %s stopped with exit code %d", e.Name, e.Win32ExitCode)
+}
+
+// HealthError reports that the health check of a running service failed
+// (see WithHealthCheck), with the exit code of the failure.
+type HealthError struct {
+	Name                    string
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+}
+
+func (e *HealthError) Error() string {
+	if e.Win32ExitCode == errorServiceSpecificError {
+		return fmt.Sprintf("winsvc: service %s is unhealthy with service specific exit code %d", e.Name, e.ServiceSpecificExitCode)
+	}
+	return fmt.Sprintf("winsvc: service %s is unhealthy with exit code %d", e.Name, e.Win32ExitCode)
+}
+
+// LockError reports that another process held the install lock (see
+// AcquireInstallLock) for longer than the operation on service Name was
+// allowed to wait.
+type LockError struct {
+	Name string // empty for AcquireInstallLock
+	Wait time.Duration
+}
+
+func (e *LockError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("winsvc: install lock held by another process after waiting %v", e.Wait)
+	}
+	return fmt.Sprintf("winsvc: service %s: install lock held by another process after waiting %v", e.Name, e.Wait)
+}
@@ -0,0 +1,49 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+// EventLogRetention is what an event log does once it is full.
+type EventLogRetention int
+
+const (
+	RetentionOverwrite EventLogRetention = iota // overwrite the oldest events
+	RetentionArchive                            // archive the full log to a file and start a new one
+	RetentionKeep                               // keep the events, dropping new ones until the log is cleared
+)
+
+// EventLogConfig is the size and retention of a dedicated event log (see
+// ConfigureEventLog).
+type EventLogConfig struct {
+	MaxSize   uint32            `json:"maxSize,omitempty" yaml:"maxSize,omitempty" toml:"maxSize,omitempty"` // in bytes, rounded up to a multiple of 64K; zero keeps the current size
+	Retention EventLogRetention `json:"retention,omitempty" yaml:"retention,omitempty" toml:"retention,omitempty"`
+}
+
+// SourceConflict is what installing a service does when its event source
+// is already registered, such as by an earlier install or by another
+// product.
+type SourceConflict int
+
+const (
+	SourceReuse  SourceConflict = iota // log as the registered source, in its log (default)
+	SourceRename                       // register the source with a numbered suffix, such as "name-2"
+	SourceFail                         // fail the install
+)
+
+// The outcomes of the event source of an install (see InstallResult).
+const (
+	SourceCreated = "Created"
+	SourceReused  = "Reused"
+	SourceRenamed = "Renamed"
+)
+
+// InstallResult tells how a service was installed (see
+// InstallWithResult): the event log and source the service logs as, and
+// whether the source was created, reused or renamed because it was
+// already registered.
+type InstallResult struct {
+	EventLog      string
+	EventSource   string
+	SourceOutcome string
+}
@@ -0,0 +1,41 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"golang.org/x/sys/windows/svc/debug"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// OpenEventLogger returns a Logger writing to the Windows event log as
+// source. Debug messages are written as Info events.
+func OpenEventLogger(source string) (Logger, error) {
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return &debugLogger{l}, nil
+}
+
+// debugLogger logs to an event log or, in debug mode, to the console.
+type debugLogger struct {
+	l debug.Log
+}
+
+func (p *debugLogger) Log(level Level, eid uint32, msg string) error {
+	switch level {
+	case LevelWarning:
+		return p.l.Warning(eid, msg)
+	case LevelError:
+		return p.l.Error(eid, msg)
+	}
+	return p.l.Info(eid, msg)
+}
+
+func (p *debugLogger) Close() error {
+	return p.l.Close()
+}
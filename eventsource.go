@@ -0,0 +1,304 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+const eventLogKeyPath = `SYSTEM\CurrentControlSet\Services\EventLog`
+
+func InstallEventSource(log, source string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.InstallEventSource(log, source)
+}
+
+func RemoveEventSource(log, source string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.RemoveEventSource(log, source)
+}
+
+func ConfigureEventLog(log string, cfg EventLogConfig) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.ConfigureEventLog(log, cfg)
+}
+
+// InstallEventSource registers source in the event log named log (the
+// Application log if empty), creating the log if needed. Messages are
+// formatted by EventCreate.exe as for the default source. A source which
+// is already registered in log is left as is.
+func (p *Manager) InstallEventSource(log, source string) error {
+	if log == "" {
+		log = "Application"
+	}
+	root, err := openLocalMachine(p.host)
+	if err != nil {
+		return fmt.Errorf("winsvc.InstallEventSource: %v", err)
+	}
+	defer closeLocalMachine(root)
+	k, _, err := registry.CreateKey(root, eventLogKeyPath+`\`+log+`\`+source, registry.SET_VALUE|keyView)
+	if err != nil {
+		return fmt.Errorf("winsvc.InstallEventSource: could not create %s\\%s: %v", log, source, err)
+	}
+	defer k.Close()
+	if err := k.SetExpandStringValue("EventMessageFile", `%SystemRoot%\System32\EventCreate.exe`); err != nil {
+		return fmt.Errorf("winsvc.InstallEventSource: %v", err)
+	}
+	if err := k.SetDWordValue("TypesSupported", eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		return fmt.Errorf("winsvc.InstallEventSource: %v", err)
+	}
+	if err := k.SetDWordValue("CustomSource", 1); err != nil {
+		return fmt.Errorf("winsvc.InstallEventSource: %v", err)
+	}
+	return nil
+}
+
+// RemoveEventSource unregisters source from the event log named log (the
+// Application log if empty). The log itself is kept.
+func (p *Manager) RemoveEventSource(log, source string) error {
+	if log == "" {
+		log = "Application"
+	}
+	root, err := openLocalMachine(p.host)
+	if err != nil {
+		return fmt.Errorf("winsvc.RemoveEventSource: %v", err)
+	}
+	defer closeLocalMachine(root)
+	if err := registry.DeleteKey(root, eventLogKeyPath+`\`+log+`\`+source); err != nil {
+		return fmt.Errorf("winsvc.RemoveEventSource: could not remove %s\\%s: %v", log, source, err)
+	}
+	return nil
+}
+
+// ConfigureEventLog sets the maximum size and retention of the event log
+// named log, creating the log if needed, so that the events of a service
+// logging there are not rotated out of the shared Application log. The
+// event log service applies the settings when it next opens the log.
+func (p *Manager) ConfigureEventLog(log string, cfg EventLogConfig) error {
+	if log == "" || strings.Contains(log, `\`) {
+		return fmt.Errorf("winsvc.ConfigureEventLog: invalid log name %q", log)
+	}
+	root, err := openLocalMachine(p.host)
+	if err != nil {
+		return fmt.Errorf("winsvc.ConfigureEventLog: %v", err)
+	}
+	defer closeLocalMachine(root)
+	k, _, err := registry.CreateKey(root, eventLogKeyPath+`\`+log, registry.SET_VALUE|keyView)
+	if err != nil {
+		return fmt.Errorf("winsvc.ConfigureEventLog: could not create %s: %v", log, err)
+	}
+	defer k.Close()
+	if cfg.MaxSize > 0 {
+		const unit = 64 << 10
+		size := (uint64(cfg.MaxSize) + unit - 1) / unit * unit
+		if size > 0xffffffff {
+			size = 0xffffffff / unit * unit
+		}
+		if err := k.SetDWordValue("MaxSize", uint32(size)); err != nil {
+			return fmt.Errorf("winsvc.ConfigureEventLog: %v", err)
+		}
+	}
+	retention, backup := uint32(0), uint32(0)
+	switch cfg.Retention {
+	case RetentionArchive:
+		retention, backup = 0xffffffff, 1
+	case RetentionKeep:
+		retention = 0xffffffff
+	}
+	if err := k.SetDWordValue("Retention", retention); err != nil {
+		return fmt.Errorf("winsvc.ConfigureEventLog: %v", err)
+	}
+	if err := k.SetDWordValue("AutoBackupLogFiles", backup); err != nil {
+		return fmt.Errorf("winsvc.ConfigureEventLog: %v", err)
+	}
+	return nil
+}
+
+// installEventSource registers the event source of a service being
+// installed as name with cfg, following cfg.SourceConflict if it is
+// already registered. A source other than the default one is recorded
+// in the key of the service for the runtime to log as it (see
+// installedEventSource), with whether the install created the source,
+// so that removing the service leaves a reused source alone.
+func (p *Manager) installEventSource(cfg ServiceConfig, name string) (InstallResult, error) {
+	r := InstallResult{EventLog: cfg.EventLog, EventSource: cfg.EventSource, SourceOutcome: SourceCreated}
+	if r.EventLog == "" {
+		r.EventLog = "Application"
+	}
+	if r.EventSource == "" {
+		r.EventSource = name
+	}
+	root, err := openLocalMachine(p.host)
+	if err != nil {
+		return r, err
+	}
+	defer closeLocalMachine(root)
+	if log, ok := findEventSource(root, r.EventSource); ok {
+		switch cfg.SourceConflict {
+		case SourceFail:
+			return r, fmt.Errorf("event source %s is already registered in the %s log", r.EventSource, log)
+		case SourceRename:
+			base := r.EventSource
+			for i := 2; ok; i++ {
+				r.EventSource = fmt.Sprintf("%s-%d", base, i)
+				_, ok = findEventSource(root, r.EventSource)
+			}
+			r.SourceOutcome = SourceRenamed
+		default:
+			// a source belongs to one log, where the service logs
+			r.EventLog, r.SourceOutcome = log, SourceReused
+		}
+	}
+	if r.SourceOutcome != SourceReused {
+		if cfg.EventLog == "" && cfg.EventSource == "" && r.SourceOutcome == SourceCreated {
+			err = eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info)
+		} else {
+			err = p.InstallEventSource(r.EventLog, r.EventSource)
+		}
+		if err != nil {
+			return r, err
+		}
+	}
+	k, err := registry.OpenKey(root, serviceKeyPath(name), registry.SET_VALUE|keyView)
+	if err != nil {
+		return r, err
+	}
+	defer k.Close()
+	if r.EventSource != name {
+		if err := k.SetStringValue("EventSource", r.EventSource); err != nil {
+			return r, err
+		}
+	}
+	created := uint32(1)
+	if r.SourceOutcome == SourceReused {
+		created = 0
+	}
+	return r, k.SetDWordValue("EventSourceCreated", created)
+}
+
+// findEventSource returns the event log source is registered in.
+func findEventSource(root registry.Key, source string) (log string, ok bool) {
+	k, err := registry.OpenKey(root, eventLogKeyPath, registry.ENUMERATE_SUB_KEYS|keyView)
+	if err != nil {
+		return "", false
+	}
+	defer k.Close()
+	logs, err := k.ReadSubKeyNames(-1)
+	if err != nil {
+		return "", false
+	}
+	for _, log := range logs {
+		if sk, err := registry.OpenKey(k, log+`\`+source, registry.QUERY_VALUE|keyView); err == nil {
+			sk.Close()
+			return log, true
+		}
+	}
+	return "", false
+}
+
+// findEventSourceUser returns an installed service other than except
+// which logs as source.
+func findEventSourceUser(root registry.Key, source, except string) (name string, ok bool) {
+	k, err := registry.OpenKey(root, `SYSTEM\CurrentControlSet\Services`, registry.ENUMERATE_SUB_KEYS|keyView)
+	if err != nil {
+		return "", false
+	}
+	defer k.Close()
+	names, err := k.ReadSubKeyNames(-1)
+	if err != nil {
+		return "", false
+	}
+	for _, name := range names {
+		if strings.EqualFold(name, except) {
+			continue
+		}
+		if s, _ := readEventSource(root, name); strings.EqualFold(s, source) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// installedEventSource returns the event source service name was
+// installed with: its name, unless installEventSource recorded another.
+func installedEventSource(name string) string {
+	source, _ := readEventSource(registry.LOCAL_MACHINE, name)
+	return source
+}
+
+// installedEventSource returns the event source service name was
+// installed with, and whether the install created it rather than reused
+// the source of another service or product.
+func (p *Manager) installedEventSource(name string) (source string, created bool) {
+	root, err := openLocalMachine(p.host)
+	if err != nil {
+		return name, false
+	}
+	defer closeLocalMachine(root)
+	return readEventSource(root, name)
+}
+
+// readEventSource reads what installEventSource recorded. Services
+// installed before it recorded whether it created the source are taken
+// to own their default source.
+func readEventSource(root registry.Key, name string) (source string, created bool) {
+	k, err := registry.OpenKey(root, serviceKeyPath(name), registry.QUERY_VALUE|keyView)
+	if err != nil {
+		return name, false
+	}
+	defer k.Close()
+	source = name
+	if s, _, err := k.GetStringValue("EventSource"); err == nil && s != "" {
+		source = s
+	}
+	n, _, err := k.GetIntegerValue("EventSourceCreated")
+	if err != nil {
+		return source, source == name
+	}
+	return source, n != 0
+}
+
+// removeInstalledEventSource unregisters source, which the install of
+// service name created, from the log it is registered in, if any. A
+// source another installed service still logs as is kept, and handed
+// over to that service so that removing it in turn removes the source.
+func (p *Manager) removeInstalledEventSource(name, source string) error {
+	root, err := openLocalMachine(p.host)
+	if err != nil {
+		return err
+	}
+	defer closeLocalMachine(root)
+	if user, ok := findEventSourceUser(root, source, name); ok {
+		k, err := registry.OpenKey(root, serviceKeyPath(user), registry.SET_VALUE|keyView)
+		if err != nil {
+			return err
+		}
+		defer k.Close()
+		return k.SetDWordValue("EventSourceCreated", 1)
+	}
+	log, ok := findEventSource(root, source)
+	if !ok {
+		return nil
+	}
+	return p.RemoveEventSource(log, source)
+}
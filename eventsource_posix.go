@@ -0,0 +1,26 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+func InstallEventSource(log, source string) error {
+	panic("winsvc: only support windows!")
+}
+func RemoveEventSource(log, source string) error {
+	panic("winsvc: only support windows!")
+}
+func ConfigureEventLog(log string, cfg EventLogConfig) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) InstallEventSource(log, source string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) RemoveEventSource(log, source string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) ConfigureEventLog(log string, cfg EventLogConfig) error {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,135 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// Expand replaces the placeholders of s: {{.Name}} by vars["Name"],
+// failing for a name vars does not have, and %NAME% by the environment
+// variable NAME, kept as is if it is not set.
+func Expand(s string, vars map[string]string) (string, error) {
+	s, err := expandTemplate(s, vars)
+	if err != nil {
+		return "", err
+	}
+	return expandEnv(s), nil
+}
+
+// ExpandParameters returns the Parameters of service name (see
+// GetParameters) with their placeholders expanded (see Expand), such as
+// when the service starts. vars adds to or overrides the built-in
+// variables of the service (see ServiceConfig.Vars).
+func ExpandParameters(name string, vars map[string]string) (map[string]string, error) {
+	params, err := GetParameters(name)
+	if err != nil {
+		return nil, err
+	}
+	appPath, _ := GetAppPath()
+	all := placeholderVars(name, appPath, vars)
+	for k, v := range params {
+		if params[k], err = Expand(v, all); err != nil {
+			return nil, fmt.Errorf("winsvc.ExpandParameters: %s: %v", k, err)
+		}
+	}
+	return params, nil
+}
+
+// placeholderVars returns the built-in variables of service name run as
+// appPath, with vars added.
+func placeholderVars(name, appPath string, vars map[string]string) map[string]string {
+	host, _ := os.Hostname()
+	all := map[string]string{
+		"ServiceName":  name,
+		"AppPath":      appPath,
+		"AppDir":       "",
+		"DataDir":      filepath.Join(os.Getenv("ProgramData"), name),
+		"ComputerName": host,
+	}
+	if appPath != "" {
+		all["AppDir"] = filepath.Dir(appPath)
+	}
+	for k, v := range vars {
+		all[k] = v
+	}
+	return all
+}
+
+// expandConfig expands the {{.Name}} placeholders of the arguments and
+// Parameters of cfg for service name installed as appPath. The %NAME%
+// ones are left for the service control manager, which expands them in
+// the command line, and for the service (see ExpandParameters).
+func expandConfig(name, appPath string, cfg *ServiceConfig) error {
+	vars := placeholderVars(name, appPath, cfg.Vars)
+	if len(cfg.Args) > 0 {
+		args := make([]string, len(cfg.Args))
+		for i, a := range cfg.Args {
+			var err error
+			if args[i], err = expandTemplate(a, vars); err != nil {
+				return fmt.Errorf("argument %d: %v", i+1, err)
+			}
+		}
+		cfg.Args = args
+	}
+	if len(cfg.Parameters) > 0 {
+		params := make(map[string]string, len(cfg.Parameters))
+		for k, v := range cfg.Parameters {
+			var err error
+			if params[k], err = expandTemplate(v, vars); err != nil {
+				return fmt.Errorf("parameter %s: %v", k, err)
+			}
+		}
+		cfg.Parameters = params
+	}
+	return nil
+}
+
+func expandTemplate(s string, vars map[string]string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	t, err := template.New("").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// expandEnv replaces the %NAME% environment variables of s, as cmd.exe
+// does.
+func expandEnv(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '%')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(s[i+1:], '%')
+		if j < 0 {
+			break
+		}
+		name := s[i+1 : i+1+j]
+		if v, ok := os.LookupEnv(name); ok && name != "" {
+			b.WriteString(s[:i])
+			b.WriteString(v)
+			s = s[i+j+2:]
+			continue
+		}
+		// not a variable, the closing % may open the next one
+		b.WriteString(s[:i+1+j])
+		s = s[i+1+j:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
@@ -0,0 +1,336 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procOpenEventLogW = modadvapi32.NewProc("OpenEventLogW")
+	procReadEventLogW = modadvapi32.NewProc("ReadEventLogW")
+	procCloseEventLog = modadvapi32.NewProc("CloseEventLog")
+)
+
+const (
+	eventlogSequentialRead = 0x0001 // EVENTLOG_SEQUENTIAL_READ
+	eventlogBackwardsRead  = 0x0008 // EVENTLOG_BACKWARDS_READ
+)
+
+// eventLogRecord is the fixed part of EVENTLOGRECORD, followed by the
+// source and computer names, the user SID, the strings and the data.
+type eventLogRecord struct {
+	Length              uint32
+	Reserved            uint32
+	RecordNumber        uint32
+	TimeGenerated       uint32
+	TimeWritten         uint32
+	EventID             uint32
+	EventType           uint16
+	NumStrings          uint16
+	EventCategory       uint16
+	ReservedFlags       uint16
+	ClosingRecordNumber uint32
+	StringOffset        uint32
+	UserSidLength       uint32
+	UserSidOffset       uint32
+	DataLength          uint32
+	DataOffset          uint32
+}
+
+// startFailureWindow is how far back ExplainStartFailure reads the event
+// logs, and maxStartFailureEntries how many entries it keeps per log.
+const (
+	startFailureWindow     = 24 * time.Hour
+	maxStartFailureEntries = 10
+)
+
+// The events of the service control manager about a failing service.
+const (
+	eventStartFailed       = 7000 // failed to start, with the error
+	eventDependencyFailed  = 7001 // a dependency failed to start
+	eventStartTimeout      = 7009 // did not connect in time
+	eventControlTimeout    = 7011 // did not answer a control in time
+	eventTerminatedError   = 7023 // terminated with an error
+	eventTerminatedSpecial = 7024 // terminated with a service specific error
+	eventCrashedRecovery   = 7031 // terminated unexpectedly, recovery taken
+	eventCrashed           = 7034 // terminated unexpectedly
+	eventLogonFailed       = 7038 // could not log on
+	eventLogonRight        = 7041 // no right to log on as a service
+)
+
+func ExplainStartFailure(name string) (*StartFailure, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.ExplainStartFailure(name)
+}
+
+// ExplainStartFailure tells why service name failed to reach Running,
+// such as with error 1053 or 1067. It puts together the status of the
+// service, its run history recorded by the runtime of this package (see
+// Stats), the service control manager entries of the System log about
+// it, and the crash reports and entries of the service in the
+// Application log (with the service name as source), and lists the
+// likely causes. It fails only if the service cannot be read.
+func (p *Manager) ExplainStartFailure(name string) (*StartFailure, error) {
+	cfg, err := p.GetServiceConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	status, err := p.QueryStatus(name)
+	if err != nil {
+		return nil, err
+	}
+	stats, _ := p.Stats(name)
+	f := &StartFailure{
+		Service:                 name,
+		State:                   status.State,
+		Win32ExitCode:           status.Win32ExitCode,
+		ServiceSpecificExitCode: status.ServiceSpecificExitCode,
+		LastStart:               stats.LastStart,
+	}
+	since := time.Now().Add(-startFailureWindow)
+	names := []string{name, cfg.DisplayName}
+	scm, err := readLogEntries(p.host, "System", since, func(e *LogEntry) bool {
+		return e.Source == "Service Control Manager" && e.Level != LevelInfo.String() && len(e.Strings) > 0 && mentions(e.Strings, names)
+	})
+	if err != nil {
+		f.cause("could not read the System log: %v", err)
+	}
+	exe := strings.ToLower(filepath.Base(binaryPath(cfg.BinaryPath)))
+	app, err := readLogEntries(p.host, "Application", since, func(e *LogEntry) bool {
+		if strings.EqualFold(e.Source, name) {
+			return true
+		}
+		return e.Source == "Application Error" && len(e.Strings) > 0 && strings.ToLower(e.Strings[0]) == exe
+	})
+	if err != nil {
+		f.cause("could not read the Application log: %v", err)
+	}
+	f.Entries = mergeLogEntries(scm, app)
+
+	// the start being explained is the one of the newest failure
+	failed := since
+	if len(scm) > 0 {
+		failed = scm[0].Time.Add(-2 * time.Minute)
+	}
+	f.ProcessStarted = stats.LastStart.After(failed)
+	f.explain(cfg, stats, scm, app)
+	return f, nil
+}
+
+// explain adds the causes of the failure, from the exit code of the
+// service or else from the newest entry of the service control manager.
+func (f *StartFailure) explain(cfg ServiceConfig, stats ServiceStats, scm, app []LogEntry) {
+	if f.State == "Running" {
+		f.cause("the service is running; the entries are about earlier failures")
+		return
+	}
+	code := windows.Errno(f.Win32ExitCode)
+	if code == 0 && len(scm) > 0 {
+		switch scm[0].EventID {
+		case eventStartTimeout, eventControlTimeout:
+			code = windows.ERROR_SERVICE_REQUEST_TIMEOUT
+		case eventCrashed, eventCrashedRecovery:
+			code = windows.ERROR_PROCESS_ABORTED
+		case eventLogonFailed, eventLogonRight:
+			code = windows.ERROR_SERVICE_LOGON_FAILED
+		case eventDependencyFailed:
+			code = windows.ERROR_SERVICE_DEPENDENCY_FAIL
+		case eventTerminatedSpecial:
+			code = errorServiceSpecificError
+		case eventStartFailed, eventTerminatedError:
+			f.cause("the service control manager reported: %s", strings.Join(scm[0].Strings[1:], "; "))
+		}
+	}
+	crashed := false
+	for _, e := range app {
+		if e.Source == "Application Error" {
+			crashed = true
+			break
+		}
+	}
+	switch code {
+	case 0:
+	case windows.ERROR_SERVICE_REQUEST_TIMEOUT:
+		if f.ProcessStarted {
+			f.cause("the service started but did not report Running in time: report start progress (see WithWaitHints), or do the slow work after reporting Running")
+		} else {
+			f.cause("the process did not connect to the service control manager in time: it is not a service binary, it does not call RunAsService early enough in main, or it blocked or crashed before")
+		}
+	case windows.ERROR_PROCESS_ABORTED:
+		if stats.Running {
+			f.cause("the process terminated without reporting Stopped: it crashed or called os.Exit")
+		} else {
+			f.cause("the process terminated before stopping cleanly")
+		}
+	case errorServiceSpecificError:
+		f.cause("the service stopped itself with service specific exit code %d (see WithInit)", f.ServiceSpecificExitCode)
+	case windows.ERROR_SERVICE_LOGON_FAILED:
+		f.cause("the service could not log on as %s: a wrong password, or no right to log on as a service (see GrantLogonRight)", accountName(cfg.Account))
+	case windows.ERROR_SERVICE_DEPENDENCY_FAIL:
+		f.cause("a dependency of the service failed to start (see DiagnoseBootStart)")
+	case windows.ERROR_FILE_NOT_FOUND, windows.ERROR_PATH_NOT_FOUND, windows.ERROR_BAD_EXE_FORMAT:
+		f.cause("the service binary %s cannot be run: %v (a path with spaces must be quoted)", cfg.BinaryPath, code)
+	default:
+		f.cause("the service stopped with exit code %d: %v", uint32(code), code)
+	}
+	if crashed {
+		f.cause("the process crashed, see the Application Error entries")
+	}
+}
+
+func accountName(account string) string {
+	if account == "" {
+		return "LocalSystem"
+	}
+	return account
+}
+
+// binaryPath returns the executable of command line cmd.
+func binaryPath(cmd string) string {
+	if strings.HasPrefix(cmd, `"`) {
+		if i := strings.Index(cmd[1:], `"`); i >= 0 {
+			return cmd[1 : i+1]
+		}
+	}
+	if i := strings.Index(strings.ToLower(cmd), ".exe"); i >= 0 {
+		return cmd[:i+len(".exe")]
+	}
+	return cmd
+}
+
+func mentions(strs, names []string) bool {
+	for _, s := range strs {
+		for _, n := range names {
+			if n != "" && strings.EqualFold(s, n) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeLogEntries merges a and b, both newest first.
+func mergeLogEntries(a, b []LogEntry) []LogEntry {
+	all := make([]LogEntry, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if a[0].Time.After(b[0].Time) {
+			all, a = append(all, a[0]), a[1:]
+		} else {
+			all, b = append(all, b[0]), b[1:]
+		}
+	}
+	return append(append(all, a...), b...)
+}
+
+// readLogEntries returns the newest entries of event log log on host for
+// which match is true, newest first, back to since and at most
+// maxStartFailureEntries.
+func readLogEntries(host, log string, since time.Time, match func(e *LogEntry) bool) ([]LogEntry, error) {
+	var server *uint16
+	if host != "" {
+		var err error
+		if server, err = windows.UTF16PtrFromString(host); err != nil {
+			return nil, err
+		}
+	}
+	source, err := windows.UTF16PtrFromString(log)
+	if err != nil {
+		return nil, err
+	}
+	r, _, e := procOpenEventLogW.Call(uintptr(unsafe.Pointer(server)), uintptr(unsafe.Pointer(source)))
+	if r == 0 {
+		return nil, e
+	}
+	defer procCloseEventLog.Call(r)
+	var entries []LogEntry
+	buf := make([]byte, 64*1024)
+	for {
+		var read, needed uint32
+		ok, _, e := procReadEventLogW.Call(r, eventlogSequentialRead|eventlogBackwardsRead, 0,
+			uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(&read)), uintptr(unsafe.Pointer(&needed)))
+		if ok == 0 {
+			switch e {
+			case windows.ERROR_HANDLE_EOF:
+				return entries, nil
+			case windows.ERROR_INSUFFICIENT_BUFFER:
+				buf = make([]byte, needed)
+				continue
+			}
+			return entries, e
+		}
+		for off := uint32(0); off+uint32(unsafe.Sizeof(eventLogRecord{})) <= read; {
+			rec := (*eventLogRecord)(unsafe.Pointer(&buf[off]))
+			if rec.Length == 0 || off+rec.Length > read {
+				break
+			}
+			entry := parseLogEntry(log, rec, buf[off:off+rec.Length])
+			if entry.Time.Before(since) {
+				return entries, nil
+			}
+			if match(&entry) {
+				entries = append(entries, entry)
+				if len(entries) == maxStartFailureEntries {
+					return entries, nil
+				}
+			}
+			off += rec.Length
+		}
+	}
+}
+
+func parseLogEntry(log string, rec *eventLogRecord, b []byte) LogEntry {
+	source, _ := utf16At(b, uint32(unsafe.Sizeof(*rec)))
+	e := LogEntry{
+		Time:    time.Unix(int64(rec.TimeGenerated), 0),
+		Log:     log,
+		Source:  source,
+		EventID: rec.EventID & 0xffff,
+		Level:   eventLevel(rec.EventType).String(),
+	}
+	off := rec.StringOffset
+	for i := 0; i < int(rec.NumStrings) && off < uint32(len(b)); i++ {
+		s, n := utf16At(b, off)
+		e.Strings = append(e.Strings, strings.TrimSpace(s))
+		off += n
+	}
+	return e
+}
+
+// utf16At returns the NUL terminated string at offset off of b, and its
+// size in bytes with the NUL.
+func utf16At(b []byte, off uint32) (string, uint32) {
+	var u []uint16
+	for i := off; i+1 < uint32(len(b)); i += 2 {
+		c := uint16(b[i]) | uint16(b[i+1])<<8
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u)), uint32(len(u)+1) * 2
+}
+
+func eventLevel(t uint16) Level {
+	switch t {
+	case windows.EVENTLOG_ERROR_TYPE, windows.EVENTLOG_AUDIT_FAILURE:
+		return LevelError
+	case windows.EVENTLOG_WARNING_TYPE:
+		return LevelWarning
+	}
+	return LevelInfo
+}
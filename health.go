@@ -0,0 +1,60 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+func CheckHealth(name string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.CheckHealth(name)
+}
+
+// CheckHealth asks service name, which runs with WithHealthCheck, to
+// check its health by sending it the Interrogate control, and returns a
+// *HealthError if the check failed. The service replies asynchronously,
+// so CheckHealth reads the outcome after Defaults().PollInterval; a
+// slower check is reported by the next call.
+func (p *Manager) CheckHealth(name string) error {
+	s, err := p.openService(name, windows.SERVICE_INTERROGATE|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return fmt.Errorf("winsvc.CheckHealth: could not access service: %v", err)
+	}
+	defer s.Close()
+	err = p.retry(func() (err error) {
+		_, err = s.Control(svc.Interrogate)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("winsvc.CheckHealth: could not interrogate service: %v", err)
+	}
+	time.Sleep(Defaults().PollInterval)
+	status, err := s.Query()
+	if err != nil {
+		return fmt.Errorf("winsvc.CheckHealth: could not query service: %v", err)
+	}
+	if status.State != svc.Running {
+		return fmt.Errorf("winsvc.CheckHealth: service %s is %s", name, stateString(status.State))
+	}
+	if status.Win32ExitCode != 0 {
+		return &HealthError{
+			Name:                    name,
+			Win32ExitCode:           status.Win32ExitCode,
+			ServiceSpecificExitCode: status.ServiceSpecificExitCode,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sys/windows"
+)
+
+var procImpersonateNamedPipeClient = modadvapi32.NewProc("ImpersonateNamedPipeClient")
+
+// ImpersonatePipeClient calls fn while impersonating the client connected
+// to the server end of the named pipe, passing the client's token, and
+// reverts to the service identity before returning. fn runs on a locked
+// OS thread and must not start goroutines expecting the client identity.
+func ImpersonatePipeClient(pipe windows.Handle, fn func(token windows.Token) error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if r1, _, e1 := procImpersonateNamedPipeClient.Call(uintptr(pipe)); r1 == 0 {
+		return fmt.Errorf("winsvc.ImpersonatePipeClient: %v", e1)
+	}
+	defer func() {
+		if err := windows.RevertToSelf(); err != nil {
+			// keeping the client identity would be a security hole
+			panic(fmt.Sprintf("winsvc.ImpersonatePipeClient: RevertToSelf failed: %v", err))
+		}
+	}()
+	var token windows.Token
+	if err := windows.OpenThreadToken(windows.CurrentThread(), windows.TOKEN_QUERY, true, &token); err != nil {
+		return fmt.Errorf("winsvc.ImpersonatePipeClient: %v", err)
+	}
+	defer token.Close()
+	return fn(token)
+}
+
+// TokenInGroup reports whether the impersonation token belongs to the
+// group sid, such as "S-1-5-32-544" for Administrators.
+func TokenInGroup(token windows.Token, sid string) (bool, error) {
+	s, err := windows.StringToSid(sid)
+	if err != nil {
+		return false, err
+	}
+	return token.IsMember(s)
+}
+
+// TokenUserName returns the account of token as DOMAIN\user.
+func TokenUserName(token windows.Token) (string, error) {
+	u, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+	account, domain, _, err := u.User.Sid.LookupAccount("")
+	if err != nil {
+		return u.User.Sid.String(), nil
+	}
+	return domain + `\` + account, nil
+}
+
+var errNoClientIdentity = errors.New("winsvc: the identity of the client is not available")
+
+// Impersonate calls fn as the client which sent req. It is only
+// available for requests received on the control pipe.
+func (req *AdminRequest) Impersonate(fn func() error) error {
+	if req.pipe == 0 {
+		return errNoClientIdentity
+	}
+	return ImpersonatePipeClient(windows.Handle(req.pipe), func(windows.Token) error {
+		return fn()
+	})
+}
+
+// ClientInGroup reports whether the client which sent req belongs to the
+// group sid. It is only available for requests received on the control
+// pipe.
+func (req *AdminRequest) ClientInGroup(sid string) (ok bool, err error) {
+	if req.pipe == 0 {
+		return false, errNoClientIdentity
+	}
+	err = ImpersonatePipeClient(windows.Handle(req.pipe), func(token windows.Token) (err error) {
+		ok, err = TokenInGroup(token, sid)
+		return err
+	})
+	return ok, err
+}
+
+// ClientUserName returns the account of the client which sent req as
+// DOMAIN\user. It is only available for requests received on the
+// control pipe.
+func (req *AdminRequest) ClientUserName() (name string, err error) {
+	if req.pipe == 0 {
+		return "", errNoClientIdentity
+	}
+	err = ImpersonatePipeClient(windows.Handle(req.pipe), func(token windows.Token) (err error) {
+		name, err = TokenUserName(token)
+		return err
+	})
+	return name, err
+}
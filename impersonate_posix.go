@@ -0,0 +1,17 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+func (req *AdminRequest) Impersonate(fn func() error) error {
+	panic("winsvc: only support windows!")
+}
+func (req *AdminRequest) ClientInGroup(sid string) (ok bool, err error) {
+	panic("winsvc: only support windows!")
+}
+func (req *AdminRequest) ClientUserName() (name string, err error) {
+	panic("winsvc: only support windows!")
+}
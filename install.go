@@ -0,0 +1,239 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func InstallServiceWithConfig(appPath, name string, cfg ServiceConfig) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.InstallWithConfig(appPath, name, cfg)
+}
+
+func InstallAndStartService(appPath, name string, cfg ServiceConfig, timeout time.Duration) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.InstallAndStart(appPath, name, cfg, timeout)
+}
+
+// InstallAndStart installs the service as InstallWithConfig does, then
+// starts it and waits up to timeout for it to be Running. If the service
+// does not start, it stays installed and the error is the one of
+// StartAndWait.
+func (p *Manager) InstallAndStart(appPath, name string, cfg ServiceConfig, timeout time.Duration) error {
+	if err := p.InstallWithConfig(appPath, name, cfg); err != nil {
+		return err
+	}
+	return p.StartAndWait(name, timeout)
+}
+
+func InstallServiceWithResult(appPath, name string, cfg ServiceConfig) (InstallResult, error) {
+	m, err := Connect()
+	if err != nil {
+		return InstallResult{}, err
+	}
+	defer m.Disconnect()
+	return m.InstallWithResult(appPath, name, cfg)
+}
+
+// InstallWithConfig installs appPath as service name configured as cfg,
+// and registers name as an event log source.
+func (p *Manager) InstallWithConfig(appPath, name string, cfg ServiceConfig) error {
+	_, err := p.InstallWithResult(appPath, name, cfg)
+	return err
+}
+
+// InstallWithResult installs the service as InstallWithConfig does, and
+// tells which event source the service logs as: an already registered
+// source is reused, renamed or fails the install following
+// cfg.SourceConflict.
+func (p *Manager) InstallWithResult(appPath, name string, cfg ServiceConfig) (result InstallResult, err error) {
+	defer func() { p.audit("Install", name, nil, auditConfig(cfg), err) }()
+	release, err := p.lockInstall(name)
+	if err != nil {
+		return result, err
+	}
+	defer release()
+	if appPath == "" {
+		appPath = cfg.BinaryPath
+	}
+	if p.host == "" {
+		if err := validateResourceStrings(&cfg); err != nil {
+			return result, fmt.Errorf("winsvc.InstallService: %v", err)
+		}
+	}
+	if err := expandConfig(name, appPath, &cfg); err != nil {
+		return result, fmt.Errorf("winsvc.InstallService: %v", err)
+	}
+	s, err := p.createService(name, appPath, cfg)
+	if err != nil {
+		return result, err
+	}
+	defer s.Close()
+	if cfg.Recovery != nil {
+		if err := writeRecovery(s, cfg.Recovery); err != nil {
+			s.Delete()
+			return result, fmt.Errorf("winsvc.InstallService: could not set recovery actions: %v", err)
+		}
+	}
+	if len(cfg.Triggers) > 0 {
+		if err := setTriggers(s.Handle, cfg.Triggers); err != nil {
+			s.Delete()
+			return result, fmt.Errorf("winsvc.InstallService: could not set triggers: %v", err)
+		}
+	}
+	if cfg.PreshutdownTimeout > 0 {
+		if err := setPreshutdownTimeout(s.Handle, cfg.PreshutdownTimeout); err != nil {
+			s.Delete()
+			return result, fmt.Errorf("winsvc.InstallService: could not set preshutdown timeout: %v", err)
+		}
+	}
+	if !cfg.SkipLogonRight {
+		if err := p.GrantLogonRight(cfg.Account); err != nil {
+			s.Delete()
+			return result, fmt.Errorf("winsvc.InstallService: %v", err)
+		}
+	}
+	if cfg.Security != "" {
+		if err := setServiceSecurity(s, cfg.Security); err != nil {
+			s.Delete()
+			return result, fmt.Errorf("winsvc.InstallService: could not set security: %v", err)
+		}
+	}
+	if len(cfg.Parameters) > 0 {
+		if err := p.SetParameters(name, cfg.Parameters); err != nil {
+			s.Delete()
+			return result, fmt.Errorf("winsvc.InstallService: %v", err)
+		}
+	}
+	if err := p.grantStatsAccess(name, cfg); err != nil {
+		s.Delete()
+		return result, fmt.Errorf("winsvc.InstallService: %v", err)
+	}
+	if cfg.EventLogConfig != nil && cfg.EventLog != "" && !strings.EqualFold(cfg.EventLog, "Application") {
+		if err := p.ConfigureEventLog(cfg.EventLog, *cfg.EventLogConfig); err != nil {
+			s.Delete()
+			return result, fmt.Errorf("winsvc.InstallService: %v", err)
+		}
+	}
+	result, err = p.installEventSource(cfg, name)
+	if err != nil {
+		s.Delete()
+		return result, fmt.Errorf("winsvc.InstallService: %v", err)
+	}
+	return result, nil
+}
+
+// defaultDeleteWait is how long Install waits by default for an earlier
+// registration of the service to be deleted.
+const defaultDeleteWait = 30 * time.Second
+
+// SetDeleteWait sets how long Install waits for a service of the same
+// name which is marked for deletion to go away. The deletion completes
+// once every handle to the service is closed, which takes a moment after
+// a Remove. Zero fails immediately.
+func (p *Manager) SetDeleteWait(d time.Duration) {
+	p.deleteWait = d
+}
+
+// createService creates service name, retrying while an earlier
+// registration of name is marked for deletion.
+func (p *Manager) createService(name, appPath string, cfg ServiceConfig) (*mgr.Service, error) {
+	deadline := time.Now().Add(p.deleteWait)
+	backoff := 250 * time.Millisecond
+	for {
+		s, err := p.openService(name, windows.SERVICE_CHANGE_CONFIG)
+		if err == nil {
+			marked := markedForDelete(s)
+			s.Close()
+			if !marked {
+				return nil, fmt.Errorf("winsvc.InstallService: service %s already exists", name)
+			}
+		} else {
+			err = p.retry(func() (err error) {
+				s, err = p.createServiceOnce(name, appPath, cfg)
+				return err
+			})
+			if err != windows.ERROR_SERVICE_MARKED_FOR_DELETE {
+				return s, err
+			}
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return nil, fmt.Errorf("winsvc.InstallService: service %s is still marked for deletion after %v; close the programs holding it open (such as services.msc) and retry", name, p.deleteWait)
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > 5*time.Second {
+			backoff = 5 * time.Second
+		}
+	}
+}
+
+// createServiceOnce creates service name through a connection opened
+// with the right to create services.
+func (p *Manager) createServiceOnce(name, appPath string, cfg ServiceConfig) (*mgr.Service, error) {
+	h, err := openSCManager(p.host, windows.SC_MANAGER_CONNECT|windows.SC_MANAGER_CREATE_SERVICE)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseServiceHandle(h)
+	return (&mgr.Mgr{Handle: h}).CreateService(name, appPath, toMgrConfig(cfg), cfg.Args...)
+}
+
+// markedForDelete reports whether s has been deleted but is kept alive by
+// open handles. A no-op configuration change fails for such a service.
+func markedForDelete(s *mgr.Service) bool {
+	const noChange = windows.SERVICE_NO_CHANGE
+	err := windows.ChangeServiceConfig(s.Handle, noChange, noChange, noChange, nil, nil, nil, nil, nil, nil, nil)
+	return err == windows.ERROR_SERVICE_MARKED_FOR_DELETE
+}
+
+func toMgrConfig(cfg ServiceConfig) mgr.Config {
+	c := mgr.Config{
+		ServiceType:      uint32(cfg.ServiceType),
+		ErrorControl:     uint32(cfg.ErrorControl),
+		LoadOrderGroup:   cfg.LoadOrderGroup,
+		Dependencies:     cfg.Dependencies,
+		ServiceStartName: cfg.Account,
+		DisplayName:      cfg.DisplayName,
+		Password:         cfg.Password,
+		Description:      cfg.Description,
+		SidType:          uint32(cfg.SidType),
+	}
+	if c.ServiceType == 0 {
+		c.ServiceType = windows.SERVICE_WIN32_OWN_PROCESS
+	}
+	if cfg.Interactive {
+		c.ServiceType |= windows.SERVICE_INTERACTIVE_PROCESS
+	}
+	c.StartType, c.DelayedAutoStart = toMgrStartType(cfg.StartType)
+	return c
+}
+
+func toMgrStartType(t StartType) (startType uint32, delayed bool) {
+	switch t {
+	case StartTypeAutomaticDelayed:
+		return windows.SERVICE_AUTO_START, true
+	case StartTypeManual:
+		return windows.SERVICE_DEMAND_START, false
+	case StartTypeDisabled:
+		return windows.SERVICE_DISABLED, false
+	}
+	return windows.SERVICE_AUTO_START, false
+}
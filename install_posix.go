@@ -0,0 +1,33 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+import (
+	"time"
+)
+
+func InstallServiceWithConfig(appPath, name string, cfg ServiceConfig) error {
+	panic("winsvc: only support windows!")
+}
+func InstallAndStartService(appPath, name string, cfg ServiceConfig, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) InstallAndStart(appPath, name string, cfg ServiceConfig, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) InstallWithConfig(appPath, name string, cfg ServiceConfig) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetDeleteWait(d time.Duration) {
+	panic("winsvc: only support windows!")
+}
+func InstallServiceWithResult(appPath, name string, cfg ServiceConfig) (InstallResult, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) InstallWithResult(appPath, name string, cfg ServiceConfig) (InstallResult, error) {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,134 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// installLockName is the machine wide mutex the installers of all the
+// processes using this package share.
+const installLockName = `Global\winsvc-install`
+
+var errLockTimeout = errors.New("install lock timeout")
+
+// installLock counts the holders of the install lock in this process,
+// which share the one hold of the mutex.
+var installLock struct {
+	sync.Mutex
+	count   int
+	release func()
+}
+
+// AcquireInstallLock takes the machine wide install lock shared by the
+// processes using this package, such as to install, update and remove
+// several services without another installer interleaving its own
+// changes, waiting up to wait for it. It fails with a *LockError if
+// another process still holds it. The lock is shared within the process,
+// so a Manager with the lock enabled (see SetInstallLock) can be used
+// while holding it. release gives it back; it must be called once.
+func AcquireInstallLock(wait time.Duration) (release func(), err error) {
+	installLock.Lock()
+	defer installLock.Unlock()
+	if installLock.count == 0 {
+		r, err := lockMutex(installLockName, wait)
+		if err == errLockTimeout {
+			return nil, &LockError{Wait: wait}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("winsvc.AcquireInstallLock: %v", err)
+		}
+		installLock.release = r
+	}
+	installLock.count++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			installLock.Lock()
+			defer installLock.Unlock()
+			installLock.count--
+			if installLock.count == 0 {
+				installLock.release()
+				installLock.release = nil
+			}
+		})
+	}, nil
+}
+
+// lockMutex takes named mutex name, waiting up to wait for it. A mutex
+// is owned by a thread, so it is taken and released by a goroutine
+// locked to its thread until release is called.
+func lockMutex(name string, wait time.Duration) (release func(), err error) {
+	n, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	acquired := make(chan error, 1)
+	done := make(chan struct{})
+	released := make(chan struct{})
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		h, err := windows.CreateMutex(nil, false, n)
+		if h == 0 {
+			acquired <- err
+			return
+		}
+		defer windows.CloseHandle(h)
+		event, err := windows.WaitForSingleObject(h, uint32(wait/time.Millisecond))
+		switch event {
+		case windows.WAIT_OBJECT_0, windows.WAIT_ABANDONED:
+			// an abandoned mutex was held by a process which exited
+			// without releasing it, it is ours now
+			acquired <- nil
+			<-done
+			windows.ReleaseMutex(h)
+			close(released)
+		case uint32(windows.WAIT_TIMEOUT):
+			acquired <- errLockTimeout
+		default:
+			acquired <- err
+		}
+	}()
+	if err := <-acquired; err != nil {
+		return nil, err
+	}
+	return func() {
+		close(done)
+		<-released
+	}, nil
+}
+
+// SetInstallLock makes the installs, updates and removes of p hold the
+// install lock (see AcquireInstallLock) while they change a service,
+// waiting up to wait for it; zero fails at once. The lock is off by
+// default (see Options.InstallLock). It only guards against the
+// installers running on this computer, so a Manager of a remote computer
+// does not take it.
+func (p *Manager) SetInstallLock(wait time.Duration) {
+	p.installLock = true
+	p.lockWait = wait
+}
+
+// lockInstall takes the install lock for a change of service name, if
+// p has it enabled.
+func (p *Manager) lockInstall(name string) (release func(), err error) {
+	if !p.installLock || p.host != "" {
+		return func() {}, nil
+	}
+	release, err = AcquireInstallLock(p.lockWait)
+	if e, ok := err.(*LockError); ok {
+		e.Name = name
+	}
+	return release, err
+}
@@ -0,0 +1,18 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+import (
+	"time"
+)
+
+func AcquireInstallLock(wait time.Duration) (release func(), err error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetInstallLock(wait time.Duration) {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,112 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"sync"
+	"testing"
+)
+
+func installLockCount() int {
+	installLock.Lock()
+	defer installLock.Unlock()
+	return installLock.count
+}
+
+// UpdateService holds the lock while calling updateConfig, which takes
+// it again: the nested take must not wait for the outer one.
+func TestInstallLockReentrant(t *testing.T) {
+	m := &Manager{installLock: true}
+	outer, err := m.lockInstall("svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := m.lockInstall("svc")
+	if err != nil {
+		t.Fatalf("nested lockInstall: %v", err)
+	}
+	if n := installLockCount(); n != 2 {
+		t.Errorf("lock held %d times, want 2", n)
+	}
+	inner()
+	inner() // a second release is ignored
+	if n := installLockCount(); n != 1 {
+		t.Errorf("lock held %d times after the inner release, want 1", n)
+	}
+	outer()
+	if n := installLockCount(); n != 0 {
+		t.Errorf("lock held %d times after the outer release, want 0", n)
+	}
+}
+
+// While the process holds the lock, the mutex is not free for another
+// holder, as it would not be for another process.
+func TestInstallLockExclusive(t *testing.T) {
+	release, err := AcquireInstallLock(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := lockMutex(installLockName, 0); err != errLockTimeout {
+		if err == nil {
+			r()
+		}
+		t.Errorf("taking the held mutex returned %v, want a timeout", err)
+	}
+	release()
+	r, err := lockMutex(installLockName, 0)
+	if err != nil {
+		t.Fatalf("taking the released mutex: %v", err)
+	}
+	r()
+}
+
+// Concurrent installs, updates and removes of one Manager share the
+// lock, and leave it free once done.
+func TestInstallLockConcurrent(t *testing.T) {
+	m := &Manager{installLock: true}
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outer, err := m.lockInstall("svc")
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer outer()
+			inner, err := m.lockInstall("svc")
+			if err != nil {
+				errs <- err
+				return
+			}
+			inner()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := installLockCount(); n != 0 {
+		t.Fatalf("lock held %d times after all released, want 0", n)
+	}
+}
+
+// A Manager of a remote computer does not take the local lock.
+func TestInstallLockRemote(t *testing.T) {
+	m := &Manager{installLock: true, host: "remote"}
+	release, err := m.lockInstall("svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if n := installLockCount(); n != 0 {
+		t.Errorf("remote Manager took the lock %d times, want 0", n)
+	}
+}
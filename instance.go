@@ -0,0 +1,31 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"strings"
+)
+
+// InstanceSeparator separates the base service name from the instance
+// name, as in "myagent$eu".
+const InstanceSeparator = "$"
+
+// InstanceServiceName returns the service name of the given instance of
+// base. An empty instance gives base itself.
+func InstanceServiceName(base, instance string) string {
+	if instance == "" {
+		return base
+	}
+	return base + InstanceSeparator + instance
+}
+
+// SplitInstanceName splits a service name such as "myagent$eu" into its
+// base and instance names. The instance is empty if name has none.
+func SplitInstanceName(name string) (base, instance string) {
+	if i := strings.Index(name, InstanceSeparator); i >= 0 {
+		return name[:i], name[i+len(InstanceSeparator):]
+	}
+	return name, ""
+}
@@ -0,0 +1,65 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/sys/windows"
+)
+
+func Inventory(filter func(info *ServiceInfo) bool) ([]ServiceInfo, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.Inventory(filter)
+}
+
+// Inventory lists the installed services sorted by name. If filter is not
+// nil, only the services for which it returns true are kept. Services
+// which go away or cannot be opened while listing are skipped.
+func (p *Manager) Inventory(filter func(info *ServiceInfo) bool) ([]ServiceInfo, error) {
+	names, err := p.m.ListServices()
+	if err != nil {
+		return nil, fmt.Errorf("winsvc.Inventory: could not list services: %v", err)
+	}
+	sort.Strings(names)
+	var list []ServiceInfo
+	for _, name := range names {
+		s, err := openService(p.m.Handle, name, windows.SERVICE_QUERY_CONFIG|windows.SERVICE_QUERY_STATUS)
+		if err != nil {
+			continue
+		}
+		c, err := s.Config()
+		if err != nil {
+			s.Close()
+			continue
+		}
+		status, err := s.Query()
+		s.Close()
+		if err != nil {
+			continue
+		}
+		cfg := fromMgrConfig(c)
+		info := ServiceInfo{
+			Name:        name,
+			DisplayName: cfg.DisplayName,
+			State:       stateString(status.State),
+			StartType:   cfg.StartType.String(),
+			BinaryPath:  c.BinaryPathName,
+			Account:     cfg.Account,
+		}
+		if filter != nil && !filter(&info) {
+			continue
+		}
+		list = append(list, info)
+	}
+	return list, nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	jobObjectMsgActiveProcessLimit = 3
+	jobObjectMsgProcessMemoryLimit = 9
+	jobObjectMsgJobMemoryLimit     = 10
+
+	jobObjectCPURateControlEnable  = 0x1
+	jobObjectCPURateControlHardCap = 0x4
+)
+
+type jobObjectAssociateCompletionPort struct {
+	CompletionKey  uintptr
+	CompletionPort windows.Handle
+}
+
+type jobObjectCPURateControlInformation struct {
+	ControlFlags uint32
+	CPURate      uint32
+}
+
+// applyJobLimits moves the service process into a new job object limited
+// as l. Processes started later by the service inherit the job, and are
+// killed when the service process exits. The job is never closed.
+func (p *serviceRuntime) applyJobLimits(l *JobLimits) error {
+	if err := l.validate(); err != nil {
+		return fmt.Errorf("winsvc: %v", err)
+	}
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return fmt.Errorf("winsvc: could not create job object: %v", err)
+	}
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{}
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if l.ProcessMemory > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_PROCESS_MEMORY
+		info.ProcessMemoryLimit = uintptr(l.ProcessMemory)
+	}
+	if l.JobMemory > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_JOB_MEMORY
+		info.JobMemoryLimit = uintptr(l.JobMemory)
+	}
+	if l.ActiveProcesses > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_ACTIVE_PROCESS
+		info.BasicLimitInformation.ActiveProcessLimit = l.ActiveProcesses
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		windows.CloseHandle(job)
+		return fmt.Errorf("winsvc: could not set job limits: %v", err)
+	}
+	if l.CPURate > 0 {
+		rate := jobObjectCPURateControlInformation{
+			ControlFlags: jobObjectCPURateControlEnable | jobObjectCPURateControlHardCap,
+			CPURate:      uint32(l.CPURate * 100),
+		}
+		if _, err := windows.SetInformationJobObject(job, windows.JobObjectCpuRateControlInformation,
+			uintptr(unsafe.Pointer(&rate)), uint32(unsafe.Sizeof(rate))); err != nil {
+			windows.CloseHandle(job)
+			return fmt.Errorf("winsvc: could not set job CPU rate: %v", err)
+		}
+	}
+	if l.Notify != nil {
+		port, err := windows.CreateIoCompletionPort(windows.InvalidHandle, 0, 0, 1)
+		if err != nil {
+			windows.CloseHandle(job)
+			return fmt.Errorf("winsvc: could not create job completion port: %v", err)
+		}
+		assoc := jobObjectAssociateCompletionPort{CompletionKey: uintptr(job), CompletionPort: port}
+		if _, err := windows.SetInformationJobObject(job, windows.JobObjectAssociateCompletionPortInformation,
+			uintptr(unsafe.Pointer(&assoc)), uint32(unsafe.Sizeof(assoc))); err != nil {
+			windows.CloseHandle(port)
+			windows.CloseHandle(job)
+			return fmt.Errorf("winsvc: could not watch job: %v", err)
+		}
+		go p.watchJob(port, l.Notify)
+	}
+	if err := windows.AssignProcessToJobObject(job, windows.CurrentProcess()); err != nil {
+		windows.CloseHandle(job)
+		return fmt.Errorf("winsvc: could not assign the service to its job: %v", err)
+	}
+	return nil
+}
+
+// watchJob passes the limit notifications of a job to notify.
+func (p *serviceRuntime) watchJob(port windows.Handle, notify func(e JobEvent)) {
+	for {
+		var msg uint32
+		var key uintptr
+		var ov *windows.Overlapped
+		if err := windows.GetQueuedCompletionStatus(port, &msg, &key, &ov, windows.INFINITE); err != nil {
+			p.elog.Error(1, fmt.Sprintf("winsvc: could not watch job: %v", err))
+			return
+		}
+		// For job notifications, the overlapped pointer holds a process ID.
+		pid := uint32(uintptr(unsafe.Pointer(ov)))
+		switch msg {
+		case jobObjectMsgProcessMemoryLimit:
+			notify(JobEvent{Limit: JobLimitProcessMemory, PID: pid})
+		case jobObjectMsgJobMemoryLimit:
+			notify(JobEvent{Limit: JobLimitJobMemory, PID: pid})
+		case jobObjectMsgActiveProcessLimit:
+			notify(JobEvent{Limit: JobLimitActiveProcesses})
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+)
+
+// JobLimits are resource limits of a job object holding the service
+// process and the processes it starts (see WithJobLimits). Zero fields
+// are not limited.
+type JobLimits struct {
+	ProcessMemory   uint64 // committed memory of each process, in bytes
+	JobMemory       uint64 // committed memory of all the processes, in bytes
+	CPURate         int    // CPU usage of all the processes, in percent of the machine (1 to 100, hard cap)
+	ActiveProcesses uint32 // number of processes alive at once
+
+	// Notify, if not nil, is called when a limit is exceeded, that is
+	// when an allocation failed or a process could not be started.
+	Notify func(e JobEvent)
+}
+
+// validate checks the limits which are out of range.
+func (l *JobLimits) validate() error {
+	if l.CPURate < 0 || l.CPURate > 100 {
+		return fmt.Errorf("job CPU rate %d%% is not between 1 and 100", l.CPURate)
+	}
+	return nil
+}
+
+// JobLimit names one of the JobLimits.
+type JobLimit int
+
+const (
+	JobLimitProcessMemory JobLimit = iota
+	JobLimitJobMemory
+	JobLimitActiveProcesses
+)
+
+func (l JobLimit) String() string {
+	switch l {
+	case JobLimitProcessMemory:
+		return "ProcessMemory"
+	case JobLimitJobMemory:
+		return "JobMemory"
+	case JobLimitActiveProcesses:
+		return "ActiveProcesses"
+	}
+	return "JobLimit(?)"
+}
+
+// JobEvent tells that a process of the job hit a limit.
+type JobEvent struct {
+	Limit JobLimit
+	PID   uint32 // the process, or zero for JobLimitActiveProcesses
+}
@@ -0,0 +1,28 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"testing"
+)
+
+func TestJobLimitsValidate(t *testing.T) {
+	for _, tt := range []struct {
+		rate int
+		ok   bool
+	}{
+		{0, true}, // not limited
+		{1, true},
+		{100, true},
+		{-1, false},
+		{101, false},
+		{1000, false},
+	} {
+		l := JobLimits{CPURate: tt.rate}
+		if err := l.validate(); (err == nil) != tt.ok {
+			t.Errorf("CPURate %d: validate() = %v", tt.rate, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"time"
+)
+
+// LifecycleHook receives the lifecycle of a running service, for example
+// to forward it to a monitoring system (see WithLifecycleHook). The
+// methods are called synchronously and must return quickly.
+type LifecycleHook interface {
+	// StateChanged is called when the service reports a new state, such
+	// as "StartPending" or "Running"; d is how long it was in from.
+	StateChanged(from, to string, d time.Duration)
+
+	// Control is called for each control request received from the
+	// service control manager, such as "Stop" or "Interrogate".
+	Control(name string)
+
+	// Command is called after each admin command, with how long it took
+	// and its error.
+	Command(name string, d time.Duration, err error)
+
+	// Stopped is called once the service stopped. reason is the control
+	// which stopped it ("Stop", "Shutdown" or "PreShutdown"), "Request"
+	// when it stopped itself, "Failed" when it stopped because of an
+	// error (see ServeUntilStopped), "InitFailed" when the WithInit function
+	// failed, "CheckFailed" when a WithCheck check failed, "CrashLoop"
+	// when WithCrashLoopBreaker refused to start it, or "Exited"
+	// when the start function returned when it
+	// should not have. exitCode is the Win32 exit code
+	// reported to the service control manager.
+	Stopped(reason string, uptime time.Duration, exitCode uint32)
+}
@@ -0,0 +1,90 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// NewFileLogger returns a Logger which appends to the file at path.
+// When the file would grow past maxSize bytes it is renamed to path.1
+// (the older ones to path.2 and so on, up to maxBackups) and a new file
+// is started. A maxSize of zero disables rotation.
+func NewFileLogger(path string, maxSize int64, maxBackups int) (Logger, error) {
+	p := &fileLogger{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := p.open(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+type fileLogger struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+func (p *fileLogger) open() error {
+	f, err := os.OpenFile(p.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	p.f = f
+	p.size = fi.Size()
+	return nil
+}
+
+func (p *fileLogger) rotate() error {
+	if err := p.f.Close(); err != nil {
+		return err
+	}
+	for i := p.maxBackups - 1; i > 0; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", p.path, i), fmt.Sprintf("%s.%d", p.path, i+1))
+	}
+	if p.maxBackups > 0 {
+		os.Rename(p.path, p.path+".1")
+	} else {
+		os.Remove(p.path)
+	}
+	return p.open()
+}
+
+func (p *fileLogger) Log(level Level, eid uint32, msg string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.f == nil {
+		return fmt.Errorf("winsvc: %s is closed", p.path)
+	}
+	line := formatLogLine(level, eid, msg)
+	if p.maxSize > 0 && p.size > 0 && p.size+int64(len(line)) > p.maxSize {
+		if err := p.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := p.f.WriteString(line)
+	p.size += int64(n)
+	return err
+}
+
+func (p *fileLogger) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.f == nil {
+		return nil
+	}
+	err := p.f.Close()
+	p.f = nil
+	return err
+}
@@ -0,0 +1,182 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log message.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarning:
+		return "warning"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Logger is a destination for the log messages of the service runtime.
+type Logger interface {
+	Log(level Level, eid uint32, msg string) error
+	Close() error
+}
+
+// FilterLogger returns a Logger which passes the messages of level min
+// and above to l and drops the others.
+func FilterLogger(l Logger, min Level) Logger {
+	return &filterLogger{l: l, min: min}
+}
+
+type filterLogger struct {
+	l   Logger
+	min Level
+}
+
+func (p *filterLogger) Log(level Level, eid uint32, msg string) error {
+	if level < p.min {
+		return nil
+	}
+	return p.l.Log(level, eid, msg)
+}
+
+func (p *filterLogger) Close() error {
+	return p.l.Close()
+}
+
+// MultiLogger returns a Logger which sends every message to all loggers.
+// Use FilterLogger on the members for per destination severities.
+func MultiLogger(loggers ...Logger) Logger {
+	return &multiLogger{loggers: append([]Logger(nil), loggers...)}
+}
+
+type multiLogger struct {
+	loggers []Logger
+}
+
+func (p *multiLogger) Log(level Level, eid uint32, msg string) (err error) {
+	for _, l := range p.loggers {
+		if e := l.Log(level, eid, msg); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+func (p *multiLogger) Close() (err error) {
+	for _, l := range p.loggers {
+		if e := l.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// NewWriterLogger returns a Logger which writes one line per message to w.
+// Close closes w if it is an io.Closer.
+func NewWriterLogger(w io.Writer) Logger {
+	return &writerLogger{w: w}
+}
+
+type writerLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (p *writerLogger) Log(level Level, eid uint32, msg string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := io.WriteString(p.w, formatLogLine(level, eid, msg))
+	return err
+}
+
+func formatLogLine(level Level, eid uint32, msg string) string {
+	return fmt.Sprintf("%s %s %d %s\n", time.Now().Format("2006-01-02 15:04:05.000"), level, eid, msg)
+}
+
+func (p *writerLogger) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// lazyLogger opens its Logger with the first message it passes, so that
+// a slow destination, such as the event log while its service starts,
+// does not delay the service start.
+type lazyLogger struct {
+	open func() (Logger, error)
+	once sync.Once
+	l    Logger
+	err  error
+}
+
+func (p *lazyLogger) logger() (Logger, error) {
+	p.once.Do(func() {
+		p.l, p.err = p.open()
+	})
+	return p.l, p.err
+}
+
+func (p *lazyLogger) Log(level Level, eid uint32, msg string) error {
+	l, err := p.logger()
+	if err != nil {
+		return err
+	}
+	return l.Log(level, eid, msg)
+}
+
+func (p *lazyLogger) Close() error {
+	opened := true
+	p.once.Do(func() {
+		opened = false
+		p.err = errors.New("winsvc: logger closed")
+	})
+	if !opened || p.l == nil {
+		return nil
+	}
+	return p.l.Close()
+}
+
+// levelLogger adds the per level helpers used by the service runtime.
+type levelLogger struct {
+	Logger
+}
+
+func (l levelLogger) Debug(eid uint32, msg string) error {
+	return l.Log(LevelDebug, eid, msg)
+}
+
+func (l levelLogger) Info(eid uint32, msg string) error {
+	return l.Log(LevelInfo, eid, msg)
+}
+
+func (l levelLogger) Warning(eid uint32, msg string) error {
+	return l.Log(LevelWarning, eid, msg)
+}
+
+func (l levelLogger) Error(eid uint32, msg string) error {
+	return l.Log(LevelError, eid, msg)
+}
@@ -0,0 +1,154 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modadvapi32                   = windows.NewLazySystemDLL("advapi32.dll")
+	procLsaOpenPolicy             = modadvapi32.NewProc("LsaOpenPolicy")
+	procLsaClose                  = modadvapi32.NewProc("LsaClose")
+	procLsaFreeMemory             = modadvapi32.NewProc("LsaFreeMemory")
+	procLsaEnumerateAccountRights = modadvapi32.NewProc("LsaEnumerateAccountRights")
+	procLsaAddAccountRights       = modadvapi32.NewProc("LsaAddAccountRights")
+)
+
+const (
+	policyCreateAccount = 0x00000010 // POLICY_CREATE_ACCOUNT
+	policyLookupNames   = 0x00000800 // POLICY_LOOKUP_NAMES
+)
+
+// The account rights about running services.
+const (
+	rightServiceLogon     = "SeServiceLogonRight"
+	rightDenyServiceLogon = "SeDenyServiceLogonRight"
+)
+
+func ntError(status uintptr) error {
+	if status == 0 {
+		return nil
+	}
+	return windows.NTStatus(uint32(status)).Errno()
+}
+
+// openPolicy opens the local security policy of host.
+func openPolicy(host string, access uint32) (windows.Handle, error) {
+	var system *windows.NTUnicodeString
+	if host != "" {
+		var err error
+		if system, err = windows.NewNTUnicodeString(host); err != nil {
+			return 0, err
+		}
+	}
+	attrs := windows.OBJECT_ATTRIBUTES{Length: uint32(unsafe.Sizeof(windows.OBJECT_ATTRIBUTES{}))}
+	var h windows.Handle
+	r, _, _ := procLsaOpenPolicy.Call(uintptr(unsafe.Pointer(system)), uintptr(unsafe.Pointer(&attrs)), uintptr(access), uintptr(unsafe.Pointer(&h)))
+	if err := ntError(r); err != nil {
+		return 0, err
+	}
+	return h, nil
+}
+
+func closePolicy(h windows.Handle) {
+	procLsaClose.Call(uintptr(h))
+}
+
+// accountRights returns the rights, such as SeServiceLogonRight, granted
+// to sid directly, not through its groups.
+func accountRights(policy windows.Handle, sid *windows.SID) ([]string, error) {
+	var rights *windows.NTUnicodeString
+	var count uint32
+	r, _, _ := procLsaEnumerateAccountRights.Call(uintptr(policy), uintptr(unsafe.Pointer(sid)), uintptr(unsafe.Pointer(&rights)), uintptr(unsafe.Pointer(&count)))
+	if windows.NTStatus(uint32(r)) == windows.STATUS_OBJECT_NAME_NOT_FOUND {
+		return nil, nil
+	}
+	if err := ntError(r); err != nil {
+		return nil, err
+	}
+	defer procLsaFreeMemory.Call(uintptr(unsafe.Pointer(rights)))
+	var list []string
+	for _, s := range unsafe.Slice(rights, count) {
+		list = append(list, s.String())
+	}
+	return list, nil
+}
+
+// addAccountRight grants right to sid.
+func addAccountRight(policy windows.Handle, sid *windows.SID, right string) error {
+	s, err := windows.NewNTUnicodeString(right)
+	if err != nil {
+		return err
+	}
+	r, _, _ := procLsaAddAccountRights.Call(uintptr(policy), uintptr(unsafe.Pointer(sid)), uintptr(unsafe.Pointer(s)), 1)
+	return ntError(r)
+}
+
+func GrantLogonRight(account string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.GrantLogonRight(account)
+}
+
+// GrantLogonRight grants account the "Log on as a service" right
+// (SeServiceLogonRight) it needs to run services, unless it has it. The
+// install functions do it for the account of the service (see
+// ServiceConfig.SkipLogonRight).
+func (p *Manager) GrantLogonRight(account string) error {
+	if !needsLogonRight(account) {
+		return nil
+	}
+	sid, err := lookupAccountSID(p.host, account)
+	if err != nil {
+		return fmt.Errorf("winsvc.GrantLogonRight: %v", err)
+	}
+	policy, err := openPolicy(p.host, policyLookupNames|policyCreateAccount)
+	if err != nil {
+		return fmt.Errorf("winsvc.GrantLogonRight: could not open the security policy: %v", err)
+	}
+	defer closePolicy(policy)
+	rights, err := accountRights(policy, sid)
+	if err != nil {
+		return fmt.Errorf("winsvc.GrantLogonRight: could not read the rights of %s: %v", account, err)
+	}
+	if hasRight(rights, rightServiceLogon) {
+		return nil
+	}
+	if err := addAccountRight(policy, sid, rightServiceLogon); err != nil {
+		return fmt.Errorf("winsvc.GrantLogonRight: could not grant %s to %s: %v", rightServiceLogon, account, err)
+	}
+	return nil
+}
+
+// needsLogonRight reports whether account must be granted the "Log on
+// as a service" right to run a service. The built-in service accounts
+// and the virtual NT SERVICE accounts have it implicitly.
+func needsLogonRight(account string) bool {
+	switch normalizeAccount(account) {
+	case AccountLocalSystem, AccountLocalService, AccountNetworkService:
+		return false
+	}
+	return !strings.HasPrefix(strings.ToLower(account), `nt service\`)
+}
+
+// hasRight reports whether right is in rights.
+func hasRight(rights []string, right string) bool {
+	for _, r := range rights {
+		if strings.EqualFold(r, right) {
+			return true
+		}
+	}
+	return false
+}
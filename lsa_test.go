@@ -0,0 +1,36 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"testing"
+)
+
+func TestNeedsLogonRight(t *testing.T) {
+	for _, tt := range []struct {
+		account string
+		want    bool
+	}{
+		{"", false},
+		{"LocalSystem", false},
+		{`.\LocalSystem`, false},
+		{`NT AUTHORITY\SYSTEM`, false},
+		{"LocalService", false},
+		{"NetworkService", false},
+		{`NT AUTHORITY\LocalService`, false},
+		{`NT AUTHORITY\Local Service`, false},
+		{`nt authority\network service`, false},
+		{`NT SERVICE\MyService`, false},
+		{`.\svcuser`, true},
+		{`CONTOSO\svc-web`, true},
+		{`CONTOSO\gmsa-web$`, true},
+	} {
+		if got := needsLogonRight(tt.account); got != tt.want {
+			t.Errorf("needsLogonRight(%q) = %v, want %v", tt.account, got, tt.want)
+		}
+	}
+}
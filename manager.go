@@ -0,0 +1,314 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Manager is a connection to the service control manager.
+// It can be reused for many operations instead of connecting for each one.
+type Manager struct {
+	m           *mgr.Mgr
+	host        string
+	deleteWait  time.Duration
+	retryPolicy *RetryPolicy
+	installLock bool
+	lockWait    time.Duration
+}
+
+// Connect connects to the local service control manager.
+func Connect() (*Manager, error) {
+	return ConnectRemote("")
+}
+
+// ConnectRemote connects to the service control manager on host.
+//
+// The connection, and the services opened through it, get only the
+// access rights each operation needs: querying or stopping a service
+// works for an account granted just that by the security descriptor of
+// the service (see GrantServiceAccess), without elevation.
+func ConnectRemote(host string) (*Manager, error) {
+	rp := DefaultRetryPolicy
+	var h windows.Handle
+	err := retryPolicy(&rp, func() (err error) {
+		h, err = openSCManager(host, windows.SC_MANAGER_CONNECT|windows.SC_MANAGER_ENUMERATE_SERVICE)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	p := &Manager{m: &mgr.Mgr{Handle: h}, host: host, deleteWait: defaultDeleteWait, retryPolicy: &rp}
+	if d := Defaults().InstallLock; d > 0 {
+		p.SetInstallLock(d)
+	}
+	return p, nil
+}
+
+func openSCManager(host string, access uint32) (windows.Handle, error) {
+	var s *uint16
+	if host != "" {
+		var err error
+		if s, err = windows.UTF16PtrFromString(host); err != nil {
+			return 0, err
+		}
+	}
+	return windows.OpenSCManager(s, nil, access)
+}
+
+func openService(scm windows.Handle, name string, access uint32) (*mgr.Service, error) {
+	s, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.OpenService(scm, s, access)
+	if err != nil {
+		return nil, err
+	}
+	return &mgr.Service{Name: name, Handle: h}, nil
+}
+
+// controlAccess returns the access rights needed to send control c.
+func controlAccess(c svc.Cmd) uint32 {
+	switch c {
+	case svc.Stop:
+		return windows.SERVICE_STOP
+	case svc.Pause, svc.Continue, svc.ParamChange,
+		svc.NetBindAdd, svc.NetBindRemove, svc.NetBindEnable, svc.NetBindDisable:
+		return windows.SERVICE_PAUSE_CONTINUE
+	case svc.Interrogate:
+		return windows.SERVICE_INTERROGATE
+	}
+	return windows.SERVICE_USER_DEFINED_CONTROL
+}
+
+func (p *Manager) Disconnect() error {
+	return p.m.Disconnect()
+}
+
+func (p *Manager) Install(appPath, name, desc string, params ...string) error {
+	return p.InstallWithConfig(appPath, name, ServiceConfig{
+		DisplayName: desc,
+		StartType:   StartTypeAutomatic,
+		Args:        params,
+	})
+}
+
+func (p *Manager) Remove(name string) error {
+	return p.remove(name, true)
+}
+
+// remove deletes service name, and the event source it was installed
+// with if source is set, the install created it and no other installed
+// service logs as it. A source which is not registered is not an error.
+func (p *Manager) remove(name string, source bool) (err error) {
+	defer func() { p.audit("Remove", name, nil, nil, err) }()
+	release, err := p.lockInstall(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+	s, err := p.openService(name, windows.DELETE)
+	if err != nil {
+		return fmt.Errorf("winsvc.RemoveService: service %s is not installed", name)
+	}
+	defer s.Close()
+	// the key of the service, where a custom source is recorded, goes
+	// with the service
+	eventSource, created := p.installedEventSource(name)
+	err = s.Delete()
+	if err != nil || !source || !created {
+		return err
+	}
+	if err := p.removeInstalledEventSource(name, eventSource); err != nil {
+		return fmt.Errorf("winsvc.RemoveService: %v", err)
+	}
+	return nil
+}
+
+func (p *Manager) Start(name string) (err error) {
+	defer func() { p.audit("Start", name, nil, nil, err) }()
+	s, err := p.openService(name, windows.SERVICE_START)
+	if err != nil {
+		return fmt.Errorf("winsvc.StartService: could not access service: %v", err)
+	}
+	defer s.Close()
+	err = p.retryAction(windows.ERROR_SERVICE_ALREADY_RUNNING, func() error { return s.Start() })
+	if err != nil {
+		return fmt.Errorf("winsvc.StartService: could not start service: %v", err)
+	}
+	return nil
+}
+
+func (p *Manager) Stop(name string) error {
+	return p.StopAndWait(name, 0)
+}
+
+func (p *Manager) Query(name string) (status string, err error) {
+	s, err := p.openService(name, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		err = fmt.Errorf("winsvc.QueryService: could not access service: %v", err)
+		return
+	}
+	defer s.Close()
+
+	var statusCode svc.Status
+	err = p.retry(func() (err error) {
+		statusCode, err = s.Query()
+		return err
+	})
+	if err != nil {
+		return
+	}
+	return stateString(statusCode.State), nil
+}
+
+// QueryStatus returns the state, process and exit code of service name.
+func (p *Manager) QueryStatus(name string) (ServiceStatus, error) {
+	s, err := p.openService(name, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return ServiceStatus{}, fmt.Errorf("winsvc.QueryService: could not access service: %v", err)
+	}
+	defer s.Close()
+	var status svc.Status
+	err = p.retry(func() (err error) {
+		status, err = s.Query()
+		return err
+	})
+	if err != nil {
+		return ServiceStatus{}, fmt.Errorf("winsvc.QueryService: %v", err)
+	}
+	return toServiceStatus(name, status), nil
+}
+
+func toServiceStatus(name string, status svc.Status) ServiceStatus {
+	return ServiceStatus{
+		Name:                    name,
+		State:                   stateString(status.State),
+		PID:                     status.ProcessId,
+		Win32ExitCode:           status.Win32ExitCode,
+		ServiceSpecificExitCode: status.ServiceSpecificExitCode,
+	}
+}
+
+func stateString(state svc.State) string {
+	switch state {
+	case svc.Stopped:
+		return "Stopped"
+	case svc.StartPending:
+		return "StartPending"
+	case svc.StopPending:
+		return "StopPending"
+	case svc.Running:
+		return "Running"
+	case svc.ContinuePending:
+		return "ContinuePending"
+	case svc.PausePending:
+		return "PausePending"
+	case svc.Paused:
+		return "Paused"
+	}
+	panic("unreached")
+}
+
+// StartAndWait starts service name and waits up to timeout until it is
+// Running; zero waits for Defaults().Timeout. If the service stops
+// instead, the error is an *ExitError with its exit code.
+func (p *Manager) StartAndWait(name string, timeout time.Duration) (err error) {
+	defer func() { p.audit("Start", name, nil, nil, err) }()
+	s, err := p.openService(name, windows.SERVICE_START|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return fmt.Errorf("winsvc.StartService: could not access service: %v", err)
+	}
+	defer s.Close()
+	err = p.retryAction(windows.ERROR_SERVICE_ALREADY_RUNNING, func() error { return s.Start() })
+	if err != nil {
+		return fmt.Errorf("winsvc.StartService: could not start service: %v", err)
+	}
+	status, err := waitState(s, svc.Running, timeout)
+	if err != nil {
+		return err
+	}
+	if status.State == svc.Stopped {
+		return &ExitError{
+			Name:                    name,
+			Win32ExitCode:           status.Win32ExitCode,
+			ServiceSpecificExitCode: status.ServiceSpecificExitCode,
+		}
+	}
+	return nil
+}
+
+// StopTimeout stops service name, waiting up to timeout for it to stop,
+// as StopAndWait does.
+func (p *Manager) StopTimeout(name string, timeout time.Duration) error {
+	return p.StopAndWait(name, timeout)
+}
+
+// StopAndWait stops service name and waits up to timeout until it is
+// Stopped; zero waits for Defaults().Timeout. Services which take long
+// to shut down cleanly need more than the default.
+func (p *Manager) StopAndWait(name string, timeout time.Duration) error {
+	return p.control(name, svc.Stop, svc.Stopped, timeout)
+}
+
+func (p *Manager) control(name string, c svc.Cmd, to svc.State, timeout time.Duration) (err error) {
+	if c == svc.Stop {
+		defer func() { p.audit("Stop", name, nil, nil, err) }()
+	}
+	s, err := p.openService(name, controlAccess(c)|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return fmt.Errorf("winsvc.controlService: could not access service: %v", err)
+	}
+	defer s.Close()
+	var status svc.Status
+	control := func() (err error) {
+		status, err = s.Control(c)
+		return err
+	}
+	if c == svc.Stop {
+		err = p.retryAction(windows.ERROR_SERVICE_NOT_ACTIVE, control)
+	} else {
+		err = p.retry(control)
+	}
+	if err != nil {
+		return fmt.Errorf("winsvc.controlService: could not send control=%d: %v", c, err)
+	}
+	if status.State == to {
+		return nil
+	}
+	_, err = waitState(s, to, timeout)
+	return err
+}
+
+// waitState polls s until it reaches state to, or until it stops. A
+// timeout of zero or less waits for Defaults().Timeout.
+func waitState(s *mgr.Service, to svc.State, timeout time.Duration) (svc.Status, error) {
+	if timeout <= 0 {
+		timeout = Defaults().Timeout
+	}
+	deadline := time.Now().Add(timeout)
+	interval := Defaults().PollInterval
+	for {
+		status, err := s.Query()
+		if err != nil {
+			return status, fmt.Errorf("winsvc.controlService: could not retrieve service status: %v", err)
+		}
+		if status.State == to || status.State == svc.Stopped {
+			return status, nil
+		}
+		if deadline.Before(time.Now()) {
+			return status, fmt.Errorf("winsvc.controlService: timeout waiting for service to go to state=%d", to)
+		}
+		time.Sleep(interval)
+	}
+}
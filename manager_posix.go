@@ -0,0 +1,50 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+import (
+	"time"
+)
+
+type Manager struct{}
+
+func Connect() (*Manager, error) {
+	panic("winsvc: only support windows!")
+}
+func ConnectRemote(host string) (*Manager, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) Disconnect() error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) Install(appPath, name, desc string, params ...string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) Remove(name string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) Start(name string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) Stop(name string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) StartAndWait(name string, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) StopTimeout(name string, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) StopAndWait(name string, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) Query(name string) (status string, err error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) QueryStatus(name string) (ServiceStatus, error) {
+	panic("winsvc: only support windows!")
+}
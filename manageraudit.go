@@ -0,0 +1,36 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// audit reports operation op on service name to the audit hook, if any.
+func (p *Manager) audit(op, name string, before, after *ServiceConfig, err error) {
+	p.auditEvent(AuditEvent{
+		Operation: op,
+		Service:   name,
+		Old:       before,
+		New:       after,
+		Err:       err,
+	})
+}
+
+// auditEvent completes e and reports it to the audit hook, if any.
+func (p *Manager) auditEvent(e AuditEvent) {
+	fn := currentAuditHook()
+	if fn == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.User, _ = TokenUserName(windows.GetCurrentProcessToken())
+	e.Host = p.host
+	fn(e)
+}
@@ -0,0 +1,112 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// metricStates are the values of the state label of the
+// winsvc_service_state metric.
+var metricStates = []string{"Stopped", "StartPending", "StopPending", "Running", "ContinuePending", "PausePending", "Paused"}
+
+func WriteMetrics(w io.Writer, names ...string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.WriteMetrics(w, names...)
+}
+
+// MetricsHandler returns an HTTP handler serving the metrics of the
+// services names to Prometheus (see WriteMetrics), connecting to the
+// service control manager for each scrape.
+func MetricsHandler(names ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := WriteMetrics(w, names...); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// WriteMetrics writes the state, uptime, start and stop counts and last
+// exit code of the services names to w in the Prometheus text exposition
+// format. The counts are those recorded by the runtime of this package
+// (see Stats). A service which cannot be queried is reported by
+// winsvc_service_scrape_error instead.
+func (p *Manager) WriteMetrics(w io.Writer, names ...string) error {
+	type sample struct {
+		status ServiceStatus
+		stats  ServiceStats
+		err    error
+	}
+	samples := make([]sample, len(names))
+	for i, name := range names {
+		samples[i].status, samples[i].err = p.QueryStatus(name)
+		if samples[i].err == nil {
+			samples[i].stats, samples[i].err = p.Stats(name)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	metric := func(name, kind, help string, value func(s sample) (float64, bool)) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for i, s := range samples {
+			if v, ok := value(s); ok {
+				fmt.Fprintf(bw, "%s{service=\"%s\"} %g\n", name, escapeLabel(names[i]), v)
+			}
+		}
+	}
+	fmt.Fprintf(bw, "# HELP winsvc_service_state The state of the service, 1 for the current one.\n# TYPE winsvc_service_state gauge\n")
+	for i, s := range samples {
+		if s.err != nil {
+			continue
+		}
+		for _, state := range metricStates {
+			v := 0
+			if s.status.State == state {
+				v = 1
+			}
+			fmt.Fprintf(bw, "winsvc_service_state{service=\"%s\",state=\"%s\"} %d\n", escapeLabel(names[i]), strings.ToLower(state), v)
+		}
+	}
+	ok := func(s sample) bool { return s.err == nil }
+	metric("winsvc_service_uptime_seconds", "gauge", "How long the service has been running.", func(s sample) (float64, bool) {
+		return s.stats.Uptime().Seconds(), ok(s)
+	})
+	metric("winsvc_service_starts_total", "counter", "Times the service started.", func(s sample) (float64, bool) {
+		return float64(s.stats.Starts), ok(s)
+	})
+	metric("winsvc_service_clean_stops_total", "counter", "Stops with a zero exit code.", func(s sample) (float64, bool) {
+		return float64(s.stats.CleanStops), ok(s)
+	})
+	metric("winsvc_service_unclean_stops_total", "counter", "Stops with an exit code, and crashes.", func(s sample) (float64, bool) {
+		return float64(s.stats.UncleanStops), ok(s)
+	})
+	metric("winsvc_service_last_exit_code", "gauge", "Win32 exit code of the last stop.", func(s sample) (float64, bool) {
+		return float64(s.stats.LastWin32ExitCode), ok(s)
+	})
+	metric("winsvc_service_last_service_specific_exit_code", "gauge", "Service specific exit code of the last stop.", func(s sample) (float64, bool) {
+		return float64(s.stats.LastServiceSpecificExitCode), ok(s)
+	})
+	metric("winsvc_service_scrape_error", "gauge", "1 if the service could not be queried.", func(s sample) (float64, bool) {
+		if s.err != nil {
+			return 1, true
+		}
+		return 0, true
+	})
+	return bw.Flush()
+}
+
+// escapeLabel escapes s for a label value of the text exposition format.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,30 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+// NetBindChange is a change of the network bindings of the service,
+// received with the NetBind* controls (see WithNetBindChange).
+type NetBindChange int
+
+const (
+	NetBindAdd     NetBindChange = iota // a new binding is available
+	NetBindRemove                       // a binding was removed
+	NetBindEnable                       // a disabled binding was enabled
+	NetBindDisable                      // a binding was disabled
+)
+
+func (c NetBindChange) String() string {
+	switch c {
+	case NetBindAdd:
+		return "NetBindAdd"
+	case NetBindRemove:
+		return "NetBindRemove"
+	case NetBindEnable:
+		return "NetBindEnable"
+	case NetBindDisable:
+		return "NetBindDisable"
+	}
+	return "NetBindChange(?)"
+}
@@ -0,0 +1,492 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"net/http"
+	"time"
+)
+
+// Accept is a set of controls a running service accepts from the service
+// control manager. The values match the Windows SERVICE_ACCEPT_* flags.
+type Accept uint32
+
+const (
+	AcceptStop                  Accept = 1 << iota // stop the service
+	AcceptPauseAndContinue                         // pause and continue the service
+	AcceptShutdown                                 // notify the service on system shutdown
+	AcceptParamChange                              // notify the service when startup parameters change
+	AcceptNetBindChange                            // notify the service of network binding changes
+	AcceptHardwareProfileChange                    // notify the service of hardware profile changes
+	AcceptPowerEvent                               // notify the service of power status changes
+	AcceptSessionChange                            // notify the service of session status changes
+	AcceptPreShutdown                              // notify the service before system shutdown
+
+	defaultAccepts = AcceptStop | AcceptShutdown | AcceptPauseAndContinue
+)
+
+// ControlReload is the custom control code asking a service to reload its
+// configuration, the equivalent of SIGHUP (see ReloadService and
+// WithReload). Custom control codes range from 128 to 255.
+const ControlReload = 128
+
+// ControlDrain is the custom control code asking a service to stop taking
+// new work and finish the work in flight, ahead of a Stop (see
+// DrainService and WithDrain).
+const ControlDrain = 129
+
+// RestartPolicy tells how to restart the start function when it returns
+// while the service is running, instead of leaving a dead workload.
+type RestartPolicy struct {
+	MaxRestarts int           // restarts before the service stops with a failure
+	Backoff     time.Duration // delay before the first restart, doubled after each one
+	MaxBackoff  time.Duration // longest delay between restarts, zero for no limit
+}
+
+// Option configures how RunAsService runs the service.
+type Option func(*options)
+
+type options struct {
+	debug            bool
+	debugController  *DebugController
+	stopTimeout      time.Duration
+	interrogateDelay time.Duration
+	interrogateOnce  bool
+	ignoreUnexpected bool
+	controlLogLevel  Level
+	health           func() error
+	startWaitHint    time.Duration
+	stopWaitHint     time.Duration
+	accepts          Accept
+	restart          *RestartPolicy
+	crashDump        bool
+	crashDumpDir     string
+	logLevel         Level
+	logRateLimit     *logRateLimit
+	shutdownLevel    uint32
+	crashLoop        CrashLoopPolicy
+	logger           Logger
+	eventSource      string
+	jobLimits        *JobLimits
+	tuning           *tuningOptions
+	sessionHelper    *sessionHelperOptions
+	keepPrivileges   []string
+	hook             LifecycleHook
+	init             func() error
+	checks           []preStartCheck
+	listeners        []listenerSpec
+	reload           func() error
+	drain            func()
+	paramChange      func(params map[string]string)
+	netBindChange    func(change NetBindChange)
+	stopReason       func(r StopReason)
+	controlPipe      *controlPipeOptions
+	adminHTTP        *adminHTTPOptions
+}
+
+type adminHTTPOptions struct {
+	addr     string
+	auth     func(r *http.Request) error
+	handlers map[string]AdminHandler
+}
+
+type logRateLimit struct {
+	window time.Duration
+	max    int
+}
+
+type tuningOptions struct {
+	defaults       ProcessTuning
+	fromParameters bool
+}
+
+type sessionHelperOptions struct {
+	appPath string
+	args    []string
+}
+
+type preStartCheck struct {
+	name  string
+	check func() error
+}
+
+type listenerSpec struct {
+	network, address string
+}
+
+type controlPipeOptions struct {
+	sddl     string
+	handlers map[string]AdminHandler
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		interrogateDelay: 100 * time.Millisecond,
+		accepts:          defaultAccepts,
+		logLevel:         LevelInfo,
+		controlLogLevel:  LevelDebug,
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// WithDebug runs the service on the console instead of under the
+// service control manager, logging to stderr (see svc/debug).
+func WithDebug(isDebug bool) Option {
+	return func(o *options) {
+		o.debug = isDebug
+	}
+}
+
+// WithDebugController runs the service in debug mode under c instead of
+// the console, so a test can give it start arguments and send it
+// controls (see NewDebugController).
+func WithDebugController(c *DebugController) Option {
+	return func(o *options) {
+		o.debug = true
+		o.debugController = c
+	}
+}
+
+// WithStopTimeout limits the time allowed to the stop callback (or to
+// start returning, for RunAsServiceContext). Zero means no limit.
+func WithStopTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.stopTimeout = d
+	}
+}
+
+// WithInterrogateDelay sets the pause between the two status reports
+// sent in reply to an Interrogate request (default 100ms).
+func WithInterrogateDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.interrogateDelay = d
+	}
+}
+
+// WithInterrogateRepeat tells whether to send the status twice in reply
+// to an Interrogate request, WithInterrogateDelay apart (default true).
+// The second report works around a deadlock of early service control
+// managers; without it Interrogate is answered at once.
+func WithInterrogateRepeat(repeat bool) Option {
+	return func(o *options) {
+		o.interrogateOnce = !repeat
+	}
+}
+
+// WithIgnoreUnexpectedControls drops the control requests the service
+// does not handle instead of logging each as an error.
+func WithIgnoreUnexpectedControls(ignore bool) Option {
+	return func(o *options) {
+		o.ignoreUnexpected = ignore
+	}
+}
+
+// WithControlLogLevel sets the severity at which every control request
+// received is logged (default LevelDebug, which the default WithLogLevel
+// drops).
+func WithControlLogLevel(l Level) Option {
+	return func(o *options) {
+		o.controlLogLevel = l
+	}
+}
+
+// WithHealthCheck runs check when the service receives the Interrogate
+// control, and reports the current status of the service with the exit
+// code of the error check returns (see WithInit for the codes), or with
+// no exit code while check succeeds. CheckHealth reads it back. check
+// delays the handling of other controls, so it must return quickly.
+// With this option the Interrogate delay has no effect.
+func WithHealthCheck(check func() error) Option {
+	return func(o *options) {
+		o.health = check
+	}
+}
+
+// WithAccepts sets the controls the service accepts
+// (default AcceptStop|AcceptShutdown|AcceptPauseAndContinue).
+func WithAccepts(a Accept) Option {
+	return func(o *options) {
+		o.accepts = a
+	}
+}
+
+// WithWaitHints sets the wait hints published with StartPending and
+// StopPending, which the service control manager uses to decide whether
+// the service hangs. While stopping, progress is reported again every
+// half stop hint. Zero publishes no hint, as before.
+func WithWaitHints(start, stop time.Duration) Option {
+	return func(o *options) {
+		o.startWaitHint = start
+		o.stopWaitHint = stop
+	}
+}
+
+// WithRestartPolicy restarts the start function as rp tells when it
+// returns unexpectedly. Once the restarts are used up the service stops
+// with a failure exit code, so the service control manager recovery
+// actions can take over.
+func WithRestartPolicy(rp RestartPolicy) Option {
+	return func(o *options) {
+		o.restart = &rp
+	}
+}
+
+// WithCrashDump writes a minidump into dir when the start function panics
+// or the service gives up restarting it. An empty dir means the directory
+// of the service binary.
+func WithCrashDump(dir string) Option {
+	return func(o *options) {
+		o.crashDump = true
+		o.crashDumpDir = dir
+	}
+}
+
+// WithLogLevel sets the lowest severity written to the event log (or to
+// the console in debug mode). The default is LevelInfo.
+func WithLogLevel(min Level) Option {
+	return func(o *options) {
+		o.logLevel = min
+	}
+}
+
+// WithShutdownLevel sets the shutdown level of the service process
+// (see SetProcessShutdownParameters), from 0x100 to 0x3FF for
+// applications: the system shuts the processes of higher levels down
+// first. It orders the process against the other processes; the order
+// of the services at shutdown is set by SetPreshutdownOrder.
+func WithShutdownLevel(level uint32) Option {
+	return func(o *options) {
+		o.shutdownLevel = level
+	}
+}
+
+// WithLogRateLimit collapses the messages the runtime logs again within
+// window into one with a count, and passes at most max different
+// messages per window, zero for no limit (see RateLimitLogger), so that
+// a service failing in a loop does not flood the event log. The limit
+// applies within one run of the service. It replaces
+// the LogRateWindow and LogRateMax defaults for this service, and a zero
+// window disables the limit. Like WithLogLevel, it does not apply to a
+// WithLogger logger.
+func WithLogRateLimit(window time.Duration, max int) Option {
+	return func(o *options) {
+		o.logRateLimit = &logRateLimit{window: window, max: max}
+	}
+}
+
+// WithLogger sends the runtime log messages to l instead of the event log
+// (or the console in debug mode). Use MultiLogger to keep the event log
+// as well. l is not closed when the service stops, and WithLogLevel does
+// not apply to it.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithJobLimits places the service process, and the processes it starts,
+// in a job object limited as l before the service starts. The processes
+// started by the service are killed when it exits.
+func WithJobLimits(l JobLimits) Option {
+	return func(o *options) {
+		o.jobLimits = &l
+	}
+}
+
+// WithProcessTuning sets the CPU affinity, priority class and I/O
+// priority of the service process when it goes Running.
+func WithProcessTuning(t ProcessTuning) Option {
+	return func(o *options) {
+		if o.tuning == nil {
+			o.tuning = &tuningOptions{}
+		}
+		o.tuning.defaults = t
+	}
+}
+
+// WithProcessTuningFromParameters is like WithProcessTuning, but values
+// stored under the Parameters key of the service (ParamCPUAffinity,
+// ParamPriorityClass and ParamIOPriority) override those of
+// WithProcessTuning, so operators can tune the service without a rebuild.
+func WithProcessTuningFromParameters() Option {
+	return func(o *options) {
+		if o.tuning == nil {
+			o.tuning = &tuningOptions{}
+		}
+		o.tuning.fromParameters = true
+	}
+}
+
+// WithSessionHelper keeps appPath running with args in the interactive
+// user sessions while the service runs, such as a tray icon application:
+// it is started in the sessions of the logged on users when the service
+// starts, and in each session a user logs on to later. The helpers are
+// killed when the service stops. The service must run as LocalSystem.
+func WithSessionHelper(appPath string, args ...string) Option {
+	return func(o *options) {
+		o.sessionHelper = &sessionHelperOptions{appPath: appPath, args: args}
+	}
+}
+
+// WithKeepPrivileges removes every privilege but names from the service
+// process once it is Running, so privileges needed only to initialize
+// are gone before the service does its work (see KeepPrivileges). The
+// service is stopped if they cannot be removed.
+func WithKeepPrivileges(names ...string) Option {
+	return func(o *options) {
+		o.keepPrivileges = append([]string{}, names...)
+	}
+}
+
+// WithInit calls init while the service is StartPending, before the
+// start function. If init fails, the service stops without ever being
+// Running, with the exit code of the error: that of an *ExitError, a
+// Win32 error code such as a windows.Errno, or service specific exit
+// code 1 for other errors.
+func WithInit(init func() error) Option {
+	return func(o *options) {
+		o.init = init
+	}
+}
+
+// WithCrashLoopBreaker keeps the service from starting while it is crash
+// looping as policy tells, going by the unclean stops recorded in its
+// stats (see Stats): it logs a "crash loop detected" error with the last
+// failures and stops with service specific exit code CrashLoopExitCode,
+// without ever being Running. Unless the recovery actions also apply to
+// non-crash failures (see RecoveryConfig), the service control manager
+// then stops restarting the service. The refusals are not counted as
+// failures, so the service starts again once the failures are older than
+// policy.Window. A policy.MaxFailures above 10, the unclean stops the
+// stats keep, is clamped to 10. The install lets the account of the
+// service write its stats; if they cannot be written, the service logs
+// a warning and the breaker does nothing.
+func WithCrashLoopBreaker(policy CrashLoopPolicy) Option {
+	return func(o *options) {
+		o.crashLoop = policy
+	}
+}
+
+// WithCheck adds check, named name, to the checks run in order while
+// the service is StartPending, before the listeners are bound and init
+// is called (see WithInit), such as CheckListen and CheckFile. If a
+// check fails, its error is logged with name and the service stops
+// without ever being Running, with the exit code of the error as for
+// WithInit, or else service specific exit code CheckFailedExitCode.
+func WithCheck(name string, check func() error) Option {
+	return func(o *options) {
+		o.checks = append(o.checks, preStartCheck{name: name, check: check})
+	}
+}
+
+// WithListener binds a listener before the service reports Running, so
+// clients never see a Running service which does not accept connections
+// yet. network is "tcp", "tcp4" or "tcp6" with a host:port address, or
+// "pipe" with a named pipe path such as `\\.\pipe\myservice`. The start
+// function gets the listeners from ServiceListeners with its context (or
+// from ServiceRuntime.Listeners), in the order of the WithListener
+// options; they are closed when the service stops. If one cannot be
+// bound, the service fails to start.
+func WithListener(network, address string) Option {
+	return func(o *options) {
+		o.listeners = append(o.listeners, listenerSpec{network, address})
+	}
+}
+
+// WithReload calls reload when the service receives ControlReload, or the
+// "reload" admin command. The outcome is written to the service log.
+func WithReload(reload func() error) Option {
+	return func(o *options) {
+		o.reload = reload
+	}
+}
+
+// WithDrain calls drain when the service receives ControlDrain, or the
+// "drain" admin command. drain stops taking new work, such as by failing
+// the health checks of a load balancer, and returns once the work in
+// flight is done. Meanwhile the service reports PausePending, then Paused,
+// so that the Stop which follows completes quickly. The service drains
+// once; Continue reports Running again but does not undo the drain.
+func WithDrain(drain func()) Option {
+	return func(o *options) {
+		o.drain = drain
+	}
+}
+
+// WithParamChange calls fn with the values of the Parameters registry key
+// of the service (see GetParameters) when the service receives the
+// ParamChange control, sent by "sc paramchange" once an administrator
+// changed them. The service accepts ParamChange with this option.
+func WithParamChange(fn func(params map[string]string)) Option {
+	return func(o *options) {
+		o.paramChange = fn
+	}
+}
+
+// WithNetBindChange calls fn when the network bindings of the service
+// change, so it can rebind. fn is called in the order of the changes
+// and delays the handling of other controls, so it must return quickly.
+// The service accepts NetBindChange with this option.
+func WithNetBindChange(fn func(change NetBindChange)) Option {
+	return func(o *options) {
+		o.netBindChange = fn
+	}
+}
+
+// WithStopReason calls fn with the reason of a stop requested with
+// StopWithReason, when the service receives the Stop control and before
+// its stop callback runs or its context is canceled. fn is not called
+// for stops without a reason, such as by "sc stop".
+func WithStopReason(fn func(r StopReason)) Option {
+	return func(o *options) {
+		o.stopReason = fn
+	}
+}
+
+// WithLifecycleHook passes the state transitions, control requests and
+// admin commands of the service to h.
+func WithLifecycleHook(h LifecycleHook) Option {
+	return func(o *options) {
+		o.hook = h
+	}
+}
+
+// WithEventSource makes the service log as event source instead of as
+// the service name, to match ServiceConfig.EventSource at install.
+func WithEventSource(source string) Option {
+	return func(o *options) {
+		o.eventSource = source
+	}
+}
+
+// WithControlPipe serves admin commands on the named pipe
+// ControlPipeName(ServiceName(ctx)) while the service runs, for clients
+// using PipeCommand. The pipe is protected by the security descriptor
+// sddl, or by one allowing only LocalSystem and Administrators if sddl
+// is empty. The "status", "diagnostics" and "shutdown" commands are
+// built in; handlers add more commands (such as "reload" or "drain")
+// or replace those.
+func WithControlPipe(sddl string, handlers map[string]AdminHandler) Option {
+	return func(o *options) {
+		o.controlPipe = &controlPipeOptions{sddl: sddl, handlers: handlers}
+	}
+}
+
+// WithAdminHTTP serves the admin commands as JSON over HTTP on the
+// loopback address addr while the service runs, for clients using
+// AdminClient. Each command is a POST to /<command>, and other methods
+// are refused. auth rejects a request by returning an error (see
+// BearerTokenAuth). It is required: any local process, and any web page
+// seen in a local browser, can reach a loopback address, so without
+// auth the endpoint is not served and an error is logged.
+// The commands are those of WithControlPipe, plus handlers.
+func WithAdminHTTP(addr string, auth func(r *http.Request) error, handlers map[string]AdminHandler) Option {
+	return func(o *options) {
+		o.adminHTTP = &adminHTTPOptions{addr: addr, auth: auth, handlers: handlers}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func serviceKeyPath(name string) string {
+	return `SYSTEM\CurrentControlSet\Services\` + name
+}
+
+func parametersKeyPath(name string) string {
+	return serviceKeyPath(name) + `\Parameters`
+}
+
+func openLocalMachine(host string) (registry.Key, error) {
+	if host == "" {
+		return registry.LOCAL_MACHINE, nil
+	}
+	return registry.OpenRemoteKey(host, registry.LOCAL_MACHINE)
+}
+
+func closeLocalMachine(k registry.Key) {
+	if k != registry.LOCAL_MACHINE {
+		k.Close()
+	}
+}
+
+// GetParameters returns the string values stored under the Parameters
+// registry key of service name.
+func GetParameters(name string) (map[string]string, error) {
+	return getParameters("", name)
+}
+
+// SetParameters stores params as string values under the Parameters
+// registry key of service name, creating the key if needed.
+func SetParameters(name string, params map[string]string) error {
+	return setParameters("", name, params)
+}
+
+func getParameters(host, name string) (map[string]string, error) {
+	hklm, err := openLocalMachine(host)
+	if err != nil {
+		return nil, err
+	}
+	defer closeLocalMachine(hklm)
+	k, err := registry.OpenKey(hklm, parametersKeyPath(name), registry.QUERY_VALUE|keyView)
+	if err != nil {
+		if err == registry.ErrNotExist {
+			return map[string]string{}, nil
+		}
+		return nil, fmt.Errorf("winsvc.GetParameters: could not open Parameters of %s: %v", name, err)
+	}
+	defer k.Close()
+	names, err := k.ReadValueNames(-1)
+	if err != nil {
+		return nil, err
+	}
+	params := make(map[string]string, len(names))
+	for _, v := range names {
+		s, _, err := k.GetStringValue(v)
+		if err != nil {
+			// not a string value
+			continue
+		}
+		params[v] = s
+	}
+	return params, nil
+}
+
+func setParameters(host, name string, params map[string]string) error {
+	hklm, err := openLocalMachine(host)
+	if err != nil {
+		return err
+	}
+	defer closeLocalMachine(hklm)
+	k, _, err := registry.CreateKey(hklm, parametersKeyPath(name), registry.SET_VALUE|keyView)
+	if err != nil {
+		return fmt.Errorf("winsvc.SetParameters: could not create Parameters of %s: %v", name, err)
+	}
+	defer k.Close()
+	for key, value := range params {
+		if err := k.SetStringValue(key, value); err != nil {
+			return fmt.Errorf("winsvc.SetParameters: could not set %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// InstallInstance installs appPath as the instance of service base (see
+// InstanceServiceName), started with args and with params stored in its
+// Parameters registry key.
+func InstallInstance(appPath, base, instance, desc string, params map[string]string, args ...string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.InstallInstance(appPath, base, instance, desc, params, args...)
+}
+
+func (p *Manager) GetParameters(name string) (map[string]string, error) {
+	return getParameters(p.host, name)
+}
+
+func (p *Manager) SetParameters(name string, params map[string]string) error {
+	return setParameters(p.host, name, params)
+}
+
+func (p *Manager) InstallInstance(appPath, base, instance, desc string, params map[string]string, args ...string) error {
+	name := InstanceServiceName(base, instance)
+	if err := p.Install(appPath, name, desc, args...); err != nil {
+		return err
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	if err := p.SetParameters(name, params); err != nil {
+		p.Remove(name)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+func GetParameters(name string) (map[string]string, error) {
+	panic("winsvc: only support windows!")
+}
+func SetParameters(name string, params map[string]string) error {
+	panic("winsvc: only support windows!")
+}
+func InstallInstance(appPath, base, instance, desc string, params map[string]string, args ...string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) GetParameters(name string) (map[string]string, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetParameters(name string, params map[string]string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) InstallInstance(appPath, base, instance, desc string, params map[string]string, args ...string) error {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,245 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// PipeCommand sends command to the control pipe of the running service
+// name (see WithControlPipe) and returns the JSON encoded result.
+func PipeCommand(name, command string, args ...string) (json.RawMessage, error) {
+	f, err := openPipe(ControlPipeName(name), 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("winsvc.PipeCommand: could not open control pipe of %s: %v", name, err)
+	}
+	defer f.Close()
+	if err := json.NewEncoder(f).Encode(adminMessage{Command: command, Args: args}); err != nil {
+		return nil, fmt.Errorf("winsvc.PipeCommand: %v", err)
+	}
+	var reply adminReply
+	if err := json.NewDecoder(f).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("winsvc.PipeCommand: %v", err)
+	}
+	if reply.Error != "" {
+		return nil, errors.New(reply.Error)
+	}
+	return reply.Result, nil
+}
+
+// openPipe opens the pipe at path, waiting while all its instances are busy.
+func openPipe(path string, timeout time.Duration) (*os.File, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err == nil {
+			return f, nil
+		}
+		if pe, ok := err.(*os.PathError); !ok || pe.Err != windows.ERROR_PIPE_BUSY || time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+type controlPipe struct {
+	p        *serviceRuntime
+	path     string
+	sa       *windows.SecurityAttributes
+	handlers map[string]AdminHandler
+
+	stop windows.Handle // event set by close
+
+	mu     sync.Mutex
+	closed bool
+	conns  map[windows.Handle]bool
+	wg     sync.WaitGroup
+}
+
+func (p *serviceRuntime) serveControlPipe() (*controlPipe, error) {
+	opts := p.opts.controlPipe
+	sddl := opts.sddl
+	if sddl == "" {
+		sddl = defaultPipeSDDL
+	}
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return nil, err
+	}
+	cp := &controlPipe{
+		p:    p,
+		path: ControlPipeName(p.serviceName()),
+		sa: &windows.SecurityAttributes{
+			SecurityDescriptor: sd,
+		},
+		conns:    make(map[windows.Handle]bool),
+		handlers: p.adminHandlers(opts.handlers),
+	}
+	cp.sa.Length = uint32(unsafe.Sizeof(*cp.sa))
+	if cp.stop, err = windows.CreateEvent(nil, 1, 0, nil); err != nil {
+		return nil, err
+	}
+	h, err := cp.create(windows.FILE_FLAG_FIRST_PIPE_INSTANCE)
+	if err != nil {
+		windows.CloseHandle(cp.stop)
+		return nil, err
+	}
+	cp.wg.Add(1)
+	go cp.serve(h)
+	return cp, nil
+}
+
+// create creates an instance of the pipe. The instances are overlapped,
+// so that close can cancel a pending ConnectNamedPipe.
+func (c *controlPipe) create(flags uint32) (windows.Handle, error) {
+	path, err := windows.UTF16PtrFromString(c.path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	return windows.CreateNamedPipe(path,
+		windows.PIPE_ACCESS_DUPLEX|windows.FILE_FLAG_OVERLAPPED|flags,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT,
+		windows.PIPE_UNLIMITED_INSTANCES, 4096, 4096, 0, c.sa)
+}
+
+func (c *controlPipe) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// serve accepts clients on pipe instance h, creating a new instance for
+// the next client each time one connects.
+func (c *controlPipe) serve(h windows.Handle) {
+	defer c.wg.Done()
+	for {
+		err := c.connect(h)
+		if c.isClosed() {
+			windows.CloseHandle(h)
+			return
+		}
+		if err != nil && err != windows.ERROR_PIPE_CONNECTED {
+			c.p.elog.Warning(1, fmt.Sprintf("winsvc.Execute: control pipe: %v", err))
+			windows.CloseHandle(h)
+		} else {
+			c.mu.Lock()
+			c.conns[h] = true
+			c.mu.Unlock()
+			go c.handle(h)
+		}
+		if h, err = c.create(0); err != nil {
+			c.p.elog.Error(1, fmt.Sprintf("winsvc.Execute: control pipe: %v", err))
+			return
+		}
+	}
+}
+
+// connect waits for a client to connect to pipe instance h, or for
+// close.
+func (c *controlPipe) connect(h windows.Handle) error {
+	return connectPipe(h, c.stop)
+}
+
+// connectPipe waits for a client to connect to the overlapped pipe
+// instance h, or for event stop to be set, which cancels the wait.
+func connectPipe(h, stop windows.Handle) error {
+	ev, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(ev)
+	ov := windows.Overlapped{HEvent: ev}
+	err = windows.ConnectNamedPipe(h, &ov)
+	if err != windows.ERROR_IO_PENDING {
+		return err
+	}
+	i, err := windows.WaitForMultipleObjects([]windows.Handle{ev, stop}, false, windows.INFINITE)
+	if err != nil {
+		return err
+	}
+	if i == windows.WAIT_OBJECT_0+1 {
+		windows.CancelIoEx(h, &ov)
+	}
+	var n uint32
+	return windows.GetOverlappedResult(h, &ov, &n, true)
+}
+
+func (c *controlPipe) handle(h windows.Handle) {
+	f := &overlappedPipe{h: h}
+	defer func() {
+		c.mu.Lock()
+		delete(c.conns, h)
+		c.mu.Unlock()
+		windows.CloseHandle(h)
+	}()
+	dec := json.NewDecoder(f)
+	enc := json.NewEncoder(f)
+	for {
+		var msg adminMessage
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		if err := enc.Encode(dispatchAdmin(c.handlers, &AdminRequest{Command: msg.Command, Args: msg.Args, pipe: uintptr(h)})); err != nil {
+			return
+		}
+	}
+}
+
+// close stops accepting clients and disconnects the connected ones.
+func (c *controlPipe) close() {
+	c.mu.Lock()
+	c.closed = true
+	for h := range c.conns {
+		windows.DisconnectNamedPipe(h)
+	}
+	c.mu.Unlock()
+	windows.SetEvent(c.stop)
+	c.wg.Wait()
+	windows.CloseHandle(c.stop)
+}
+
+// overlappedPipe does blocking reads and writes on an overlapped pipe
+// instance.
+type overlappedPipe struct {
+	h windows.Handle
+}
+
+func (p *overlappedPipe) Read(b []byte) (int, error) {
+	n, err := p.do(windows.ReadFile, b)
+	if err == windows.ERROR_BROKEN_PIPE || err == windows.ERROR_PIPE_NOT_CONNECTED || (err == nil && n == 0 && len(b) > 0) {
+		return n, io.EOF
+	}
+	return n, err
+}
+
+func (p *overlappedPipe) Write(b []byte) (int, error) {
+	return p.do(windows.WriteFile, b)
+}
+
+func (p *overlappedPipe) do(op func(windows.Handle, []byte, *uint32, *windows.Overlapped) error, b []byte) (int, error) {
+	ev, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(ev)
+	ov := windows.Overlapped{HEvent: ev}
+	var n uint32
+	err = op(p.h, b, &n, &ov)
+	if err == windows.ERROR_IO_PENDING {
+		err = windows.GetOverlappedResult(p.h, &ov, &n, true)
+	}
+	return int(n), err
+}
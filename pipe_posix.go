@@ -0,0 +1,15 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+import (
+	"encoding/json"
+)
+
+func PipeCommand(name, command string, args ...string) (json.RawMessage, error) {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,159 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// ListenPipe listens on the named pipe path, such as `\\.\pipe\myservice`.
+// The pipe is protected by the security descriptor sddl, or by the
+// default one of named pipes if sddl is empty. The connections do not
+// support deadlines.
+func ListenPipe(path, sddl string) (net.Listener, error) {
+	l := &pipeListener{path: path}
+	if sddl != "" {
+		sd, err := windows.SecurityDescriptorFromString(sddl)
+		if err != nil {
+			return nil, err
+		}
+		l.sa = &windows.SecurityAttributes{SecurityDescriptor: sd}
+		l.sa.Length = uint32(unsafe.Sizeof(*l.sa))
+	}
+	stop, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, &net.OpError{Op: "listen", Net: "pipe", Addr: pipeAddr(path), Err: err}
+	}
+	h, err := l.create(windows.FILE_FLAG_FIRST_PIPE_INSTANCE)
+	if err != nil {
+		windows.CloseHandle(stop)
+		return nil, &net.OpError{Op: "listen", Net: "pipe", Addr: pipeAddr(path), Err: err}
+	}
+	l.next, l.stop = h, stop
+	return l, nil
+}
+
+type pipeListener struct {
+	path string
+	sa   *windows.SecurityAttributes
+	stop windows.Handle // event set by Close
+
+	acceptMu sync.Mutex // held by Accept
+	next     windows.Handle
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// create creates an instance of the pipe. The instances are overlapped,
+// so that Close can cancel a pending ConnectNamedPipe.
+func (l *pipeListener) create(flags uint32) (windows.Handle, error) {
+	path, err := windows.UTF16PtrFromString(l.path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	return windows.CreateNamedPipe(path,
+		windows.PIPE_ACCESS_DUPLEX|windows.FILE_FLAG_OVERLAPPED|flags,
+		windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT,
+		windows.PIPE_UNLIMITED_INSTANCES, 4096, 4096, 0, l.sa)
+}
+
+func (l *pipeListener) isClosed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closed
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.acceptMu.Lock()
+	defer l.acceptMu.Unlock()
+	for {
+		if l.isClosed() || l.next == windows.InvalidHandle {
+			return nil, net.ErrClosed
+		}
+		h := l.next
+		err := connectPipe(h, l.stop)
+		if l.isClosed() {
+			windows.CloseHandle(h)
+			l.next = windows.InvalidHandle
+			return nil, net.ErrClosed
+		}
+		next, cerr := l.create(0)
+		if cerr != nil {
+			next = windows.InvalidHandle
+		}
+		l.next = next
+		if err != nil && err != windows.ERROR_PIPE_CONNECTED {
+			windows.CloseHandle(h)
+			if cerr != nil {
+				return nil, &net.OpError{Op: "accept", Net: "pipe", Addr: pipeAddr(l.path), Err: cerr}
+			}
+			continue
+		}
+		return &pipeConn{overlappedPipe: overlappedPipe{h: h}, addr: pipeAddr(l.path)}, nil
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.mu.Unlock()
+	// cancel a pending connect, which Accept waits for holding acceptMu
+	windows.SetEvent(l.stop)
+	l.acceptMu.Lock()
+	defer l.acceptMu.Unlock()
+	if l.next != windows.InvalidHandle {
+		windows.CloseHandle(l.next)
+		l.next = windows.InvalidHandle
+	}
+	windows.CloseHandle(l.stop)
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr(l.path)
+}
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+var errPipeDeadline = errors.New("winsvc: pipe connections do not support deadlines")
+
+// pipeConn is a connected overlapped pipe instance.
+type pipeConn struct {
+	overlappedPipe
+	addr      pipeAddr
+	closeOnce sync.Once
+}
+
+// Close cancels the pending reads and writes and closes the instance.
+func (c *pipeConn) Close() error {
+	err := net.ErrClosed
+	c.closeOnce.Do(func() {
+		windows.CancelIoEx(c.h, nil)
+		err = windows.CloseHandle(c.h)
+	})
+	return err
+}
+
+func (c *pipeConn) LocalAddr() net.Addr                { return c.addr }
+func (c *pipeConn) RemoteAddr() net.Addr               { return c.addr }
+func (c *pipeConn) SetDeadline(t time.Time) error      { return errPipeDeadline }
+func (c *pipeConn) SetReadDeadline(t time.Time) error  { return errPipeDeadline }
+func (c *pipeConn) SetWriteDeadline(t time.Time) error { return errPipeDeadline }
@@ -0,0 +1,89 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"strings"
+	"time"
+)
+
+// Well known service accounts.
+const (
+	AccountLocalSystem    = "LocalSystem"
+	AccountLocalService   = `NT AUTHORITY\LocalService`
+	AccountNetworkService = `NT AUTHORITY\NetworkService`
+)
+
+// normalizeAccount maps the names of the built-in accounts, such as
+// LocalService, "NT AUTHORITY\Local Service" or .\LocalSystem, to their
+// Account constant, and an empty account to AccountLocalSystem. Other
+// accounts are returned as they are, to be compared ignoring case.
+func normalizeAccount(account string) string {
+	a := strings.ToLower(account)
+	for _, domain := range []string{`.\`, `nt authority\`} {
+		a = strings.TrimPrefix(a, domain)
+	}
+	switch strings.Replace(a, " ", "", -1) {
+	case "", "localsystem", "system":
+		return AccountLocalSystem
+	case "localservice":
+		return AccountLocalService
+	case "networkservice":
+		return AccountNetworkService
+	}
+	return account
+}
+
+// PresetNetworkService returns the configuration of a typical network
+// server: delayed automatic start after the TCP/IP stack, started again
+// once the machine gets an IP address, running as NetworkService with
+// its own service SID, and restarted when it fails.
+func PresetNetworkService(displayName, description string) ServiceConfig {
+	return ServiceConfig{
+		DisplayName:  displayName,
+		Description:  description,
+		StartType:    StartTypeAutomaticDelayed,
+		ErrorControl: ErrorNormal,
+		Dependencies: []string{"Tcpip"},
+		Account:      AccountNetworkService,
+		SidType:      SidTypeUnrestricted,
+		Recovery:     presetRecovery(),
+		Triggers: []Trigger{{
+			Type:    TriggerIPAddressAvailability,
+			Action:  TriggerActionStart,
+			Subtype: TriggerSubtypeFirstIPAddressArrival,
+		}},
+	}
+}
+
+// PresetBackgroundWorker returns the configuration of a background
+// worker: started on demand, running as LocalService with its own
+// service SID, and restarted when it fails. Such workers usually do not
+// support pausing; run them with WithAccepts(AcceptStop|AcceptShutdown).
+func PresetBackgroundWorker(displayName, description string) ServiceConfig {
+	return ServiceConfig{
+		DisplayName:  displayName,
+		Description:  description,
+		StartType:    StartTypeManual,
+		ErrorControl: ErrorNormal,
+		Account:      AccountLocalService,
+		SidType:      SidTypeUnrestricted,
+		Recovery:     presetRecovery(),
+	}
+}
+
+// presetRecovery restarts the service after 5s, then 30s, then every
+// minute, forgetting the failures after a day.
+func presetRecovery() *RecoveryConfig {
+	return &RecoveryConfig{
+		Actions: []RecoveryAction{
+			{Type: RecoveryRestart, Delay: 5 * time.Second},
+			{Type: RecoveryRestart, Delay: 30 * time.Second},
+			{Type: RecoveryRestart, Delay: time.Minute},
+		},
+		ResetPeriod:        24 * time.Hour,
+		OnNonCrashFailures: true,
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// controlKeyPath holds the PreshutdownOrder value, the services notified
+// first of the system shutdown, in order.
+const controlKeyPath = `SYSTEM\CurrentControlSet\Control`
+
+func queryPreshutdownTimeout(h windows.Handle) (time.Duration, error) {
+	b, err := queryServiceConfig2(h, windows.SERVICE_CONFIG_PRESHUTDOWN_INFO)
+	if err != nil {
+		return 0, err
+	}
+	ms := *(*uint32)(unsafe.Pointer(&b[0]))
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func setPreshutdownTimeout(h windows.Handle, d time.Duration) error {
+	ms := uint32(d / time.Millisecond)
+	return windows.ChangeServiceConfig2(h, windows.SERVICE_CONFIG_PRESHUTDOWN_INFO, (*byte)(unsafe.Pointer(&ms)))
+}
+
+func SetPreshutdownTimeout(name string, d time.Duration) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.SetPreshutdownTimeout(name, d)
+}
+
+// SetPreshutdownTimeout changes how long the system shutdown waits for
+// service name to stop once it is sent PreShutdown (see
+// ServiceConfig.PreshutdownTimeout).
+func (p *Manager) SetPreshutdownTimeout(name string, d time.Duration) (err error) {
+	defer func() { p.audit("Update", name, nil, nil, err) }()
+	s, err := p.openService(name, windows.SERVICE_CHANGE_CONFIG)
+	if err != nil {
+		return fmt.Errorf("winsvc.SetPreshutdownTimeout: could not access service: %v", err)
+	}
+	defer s.Close()
+	if err := setPreshutdownTimeout(s.Handle, d); err != nil {
+		return fmt.Errorf("winsvc.SetPreshutdownTimeout: %v", err)
+	}
+	return nil
+}
+
+// PreshutdownOrder returns the services which the system shutdown sends
+// PreShutdown first, one after the other, before the other services
+// accepting it.
+func PreshutdownOrder() ([]string, error) {
+	return preshutdownOrder("")
+}
+
+// SetPreshutdownOrder moves service name in the preshutdown order just
+// ahead of the first of before found there, or to the end, so that a
+// service which flushes its data is stopped before the storage service
+// it depends on, such as SetPreshutdownOrder("myagent", "MSSQLSERVER").
+// The services of before missing from the order are added after name.
+// The services should accept PreShutdown (see AcceptPreShutdown).
+func SetPreshutdownOrder(name string, before ...string) error {
+	return setPreshutdownOrder("", name, before)
+}
+
+func (p *Manager) PreshutdownOrder() ([]string, error) {
+	return preshutdownOrder(p.host)
+}
+
+func (p *Manager) SetPreshutdownOrder(name string, before ...string) (err error) {
+	defer func() { p.audit("Update", name, nil, nil, err) }()
+	return setPreshutdownOrder(p.host, name, before)
+}
+
+func preshutdownOrder(host string) ([]string, error) {
+	hklm, err := openLocalMachine(host)
+	if err != nil {
+		return nil, fmt.Errorf("winsvc.PreshutdownOrder: %v", err)
+	}
+	defer closeLocalMachine(hklm)
+	k, err := registry.OpenKey(hklm, controlKeyPath, registry.QUERY_VALUE|keyView)
+	if err != nil {
+		return nil, fmt.Errorf("winsvc.PreshutdownOrder: %v", err)
+	}
+	defer k.Close()
+	order, _, err := k.GetStringsValue("PreshutdownOrder")
+	if err != nil && err != registry.ErrNotExist {
+		return nil, fmt.Errorf("winsvc.PreshutdownOrder: %v", err)
+	}
+	return order, nil
+}
+
+func setPreshutdownOrder(host, name string, before []string) error {
+	hklm, err := openLocalMachine(host)
+	if err != nil {
+		return fmt.Errorf("winsvc.SetPreshutdownOrder: %v", err)
+	}
+	defer closeLocalMachine(hklm)
+	k, err := registry.OpenKey(hklm, controlKeyPath, registry.QUERY_VALUE|registry.SET_VALUE|keyView)
+	if err != nil {
+		return fmt.Errorf("winsvc.SetPreshutdownOrder: %v", err)
+	}
+	defer k.Close()
+	order, _, err := k.GetStringsValue("PreshutdownOrder")
+	if err != nil && err != registry.ErrNotExist {
+		return fmt.Errorf("winsvc.SetPreshutdownOrder: %v", err)
+	}
+	if err := k.SetStringsValue("PreshutdownOrder", orderBefore(order, name, before)); err != nil {
+		return fmt.Errorf("winsvc.SetPreshutdownOrder: %v", err)
+	}
+	return nil
+}
+
+// orderBefore returns order with name moved ahead of the first of before
+// it holds, and the missing services of before appended.
+func orderBefore(order []string, name string, before []string) []string {
+	index := func(list []string, s string) int {
+		for i, v := range list {
+			if strings.EqualFold(v, s) {
+				return i
+			}
+		}
+		return -1
+	}
+	var out []string
+	for _, v := range order {
+		if !strings.EqualFold(v, name) {
+			out = append(out, v)
+		}
+	}
+	at := len(out)
+	for _, b := range before {
+		if i := index(out, b); i >= 0 && i < at {
+			at = i
+		}
+	}
+	out = append(out[:at], append([]string{name}, out[at:]...)...)
+	for _, b := range before {
+		if index(out, b) < 0 {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// applyShutdownLevel sets the shutdown level of the service process (see
+// WithShutdownLevel).
+func (p *serviceRuntime) applyShutdownLevel() {
+	if p.opts.shutdownLevel == 0 {
+		return
+	}
+	if err := windows.SetProcessShutdownParameters(p.opts.shutdownLevel, 0); err != nil {
+		p.elog.Warning(1, fmt.Sprintf("winsvc.RunAsService: could not set shutdown level %#x: %v", p.opts.shutdownLevel, err))
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+import "time"
+
+func SetPreshutdownTimeout(name string, d time.Duration) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetPreshutdownTimeout(name string, d time.Duration) (err error) {
+	panic("winsvc: only support windows!")
+}
+func PreshutdownOrder() ([]string, error) {
+	panic("winsvc: only support windows!")
+}
+func SetPreshutdownOrder(name string, before ...string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) PreshutdownOrder() ([]string, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetPreshutdownOrder(name string, before ...string) (err error) {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,97 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// RemovePrivileges removes the privileges names, such as "SeDebugPrivilege",
+// from the token of the current process. Removed privileges cannot be
+// enabled again for the life of the process. Unknown names are an error,
+// privileges the token does not hold are ignored.
+func RemovePrivileges(names ...string) error {
+	drop := make(map[windows.LUID]bool)
+	for _, name := range names {
+		luid, err := lookupPrivilege(name)
+		if err != nil {
+			return fmt.Errorf("winsvc.RemovePrivileges: %v", err)
+		}
+		drop[luid] = true
+	}
+	if err := removePrivileges(func(luid windows.LUID) bool { return drop[luid] }); err != nil {
+		return fmt.Errorf("winsvc.RemovePrivileges: %v", err)
+	}
+	return nil
+}
+
+// KeepPrivileges removes every privilege but names from the token of the
+// current process, as RemovePrivileges does. Most services should keep
+// "SeChangeNotifyPrivilege", needed to traverse directories.
+func KeepPrivileges(names ...string) error {
+	keep := make(map[windows.LUID]bool)
+	for _, name := range names {
+		luid, err := lookupPrivilege(name)
+		if err != nil {
+			return fmt.Errorf("winsvc.KeepPrivileges: %v", err)
+		}
+		keep[luid] = true
+	}
+	if err := removePrivileges(func(luid windows.LUID) bool { return !keep[luid] }); err != nil {
+		return fmt.Errorf("winsvc.KeepPrivileges: %v", err)
+	}
+	return nil
+}
+
+func lookupPrivilege(name string) (luid windows.LUID, err error) {
+	s, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return luid, err
+	}
+	if err = windows.LookupPrivilegeValue(nil, s, &luid); err != nil {
+		return luid, fmt.Errorf("unknown privilege %s: %v", name, err)
+	}
+	return luid, nil
+}
+
+// removePrivileges removes the privileges of the process token for which
+// drop returns true.
+func removePrivileges(drop func(luid windows.LUID) bool) error {
+	var token windows.Token
+	if err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_QUERY|windows.TOKEN_ADJUST_PRIVILEGES, &token); err != nil {
+		return err
+	}
+	defer token.Close()
+
+	var n uint32
+	windows.GetTokenInformation(token, windows.TokenPrivileges, nil, 0, &n)
+	if n == 0 {
+		return nil
+	}
+	buf := make([]byte, n)
+	if err := windows.GetTokenInformation(token, windows.TokenPrivileges, &buf[0], n, &n); err != nil {
+		return err
+	}
+	privs := (*windows.Tokenprivileges)(unsafe.Pointer(&buf[0]))
+	all := privs.AllPrivileges()
+	// keep the privileges to drop at the start of the list, in place
+	count := 0
+	for _, p := range all {
+		if drop(p.Luid) {
+			all[count] = windows.LUIDAndAttributes{Luid: p.Luid, Attributes: windows.SE_PRIVILEGE_REMOVED}
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	privs.PrivilegeCount = uint32(count)
+	return windows.AdjustTokenPrivileges(token, false, privs, 0, nil, nil)
+}
@@ -0,0 +1,14 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+func RemovePrivileges(names ...string) error {
+	panic("winsvc: only support windows!")
+}
+func KeepPrivileges(names ...string) error {
+	panic("winsvc: only support windows!")
+}
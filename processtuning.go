@@ -0,0 +1,57 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procSetProcessAffinityMask = windows.NewLazySystemDLL("kernel32.dll").NewProc("SetProcessAffinityMask")
+
+// applyTuning applies the process tuning options, once the service is
+// Running. Failures are logged, the service keeps running.
+func (p *serviceRuntime) applyTuning() {
+	t := p.opts.tuning.defaults
+	if p.opts.tuning.fromParameters {
+		params, err := GetParameters(p.serviceName())
+		if err == nil {
+			t, err = tuningFromParameters(t, params)
+		}
+		if err != nil {
+			p.elog.Error(1, fmt.Sprintf("winsvc.Execute: could not read process tuning: %v", err))
+			return
+		}
+	}
+	if err := setProcessTuning(t); err != nil {
+		p.elog.Error(1, fmt.Sprintf("winsvc.Execute: could not tune the service process: %v", err))
+	}
+}
+
+func setProcessTuning(t ProcessTuning) error {
+	h := windows.CurrentProcess()
+	if t.Affinity != 0 {
+		r1, _, e1 := procSetProcessAffinityMask.Call(uintptr(h), uintptr(t.Affinity))
+		if r1 == 0 {
+			return fmt.Errorf("SetProcessAffinityMask: %v", e1)
+		}
+	}
+	if t.PriorityClass != 0 {
+		if err := windows.SetPriorityClass(h, uint32(t.PriorityClass)); err != nil {
+			return fmt.Errorf("SetPriorityClass: %v", err)
+		}
+	}
+	if t.IOPriority != IOPriorityDefault {
+		prio := uint32(t.IOPriority - IOPriorityVeryLow)
+		if err := windows.NtSetInformationProcess(h, windows.ProcessIoPriority, unsafe.Pointer(&prio), uint32(unsafe.Sizeof(prio))); err != nil {
+			return fmt.Errorf("NtSetInformationProcess: %v", err)
+		}
+	}
+	return nil
+}
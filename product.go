@@ -0,0 +1,58 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+)
+
+// Product is a suite of services installed, upgraded and removed as a
+// whole. The services log to one shared event source and, unless their
+// Config sets its own, share one recovery policy. Dependencies between
+// the services are taken from their Config.Dependencies.
+type Product struct {
+	Name string
+
+	// EventLog and EventSource are the shared event source of the
+	// services; empty means the Application log and Name.
+	EventLog       string
+	EventSource    string
+	EventLogConfig *EventLogConfig // size and retention of EventLog
+
+	Recovery *RecoveryConfig // for services with no Config.Recovery
+	Services []ServiceSpec
+}
+
+// eventLog returns the log and source name of the shared event source.
+func (prod *Product) eventLog() (log, source string) {
+	source = prod.EventSource
+	if source == "" {
+		source = prod.Name
+	}
+	return prod.EventLog, source
+}
+
+// specs returns the services of prod with the shared settings applied.
+func (prod *Product) specs() ([]ServiceSpec, error) {
+	log, source := prod.eventLog()
+	if source == "" {
+		return nil, fmt.Errorf("winsvc: product has neither Name nor EventSource")
+	}
+	specs := make([]ServiceSpec, len(prod.Services))
+	for i, s := range prod.Services {
+		s.Config.EventLog, s.Config.EventSource = log, source
+		if s.Config.EventLogConfig == nil {
+			s.Config.EventLogConfig = prod.EventLogConfig
+		}
+		if s.Config.Recovery == nil {
+			s.Config.Recovery = prod.Recovery
+		}
+		specs[i] = s
+	}
+	if _, err := batchOrder(specs, false); err != nil {
+		return nil, fmt.Errorf("winsvc: product %s: %v", prod.Name, err)
+	}
+	return specs, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+func InstallProduct(prod *Product) ([]Result, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.InstallProduct(prod)
+}
+
+func UpgradeProduct(prod *Product) ([]Result, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.UpgradeProduct(prod)
+}
+
+func RemoveProduct(prod *Product) ([]Result, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.RemoveProduct(prod)
+}
+
+// InstallProduct installs the services of prod, each after the ones it
+// depends on. If any fails, the services installed so far are removed
+// again with the event source their install created, so that prod is
+// installed completely or not at all.
+func (p *Manager) InstallProduct(prod *Product) ([]Result, error) {
+	specs, err := prod.specs()
+	if err != nil {
+		return nil, err
+	}
+	results, err := p.InstallAll(specs)
+	if err != nil {
+		for _, r := range results {
+			if r.Err == nil {
+				p.remove(r.Name, true)
+			}
+		}
+		return results, fmt.Errorf("winsvc.InstallProduct: %s: %v", prod.Name, err)
+	}
+	return results, nil
+}
+
+// UpgradeProduct brings the installed services of prod in line with it:
+// services already installed are reconfigured (see UpdateService), the
+// others are installed. Running services keep running with their old
+// binary until restarted.
+func (p *Manager) UpgradeProduct(prod *Product) ([]Result, error) {
+	specs, err := prod.specs()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.InstallEventSource(prod.eventLog()); err != nil {
+		return nil, fmt.Errorf("winsvc.UpgradeProduct: %s: %v", prod.Name, err)
+	}
+	results, err := runBatch(specs, false, func(s ServiceSpec) error {
+		installed, err := p.installed(s.Name)
+		if err != nil {
+			return err
+		}
+		if !installed {
+			return p.InstallWithConfig(s.AppPath, s.Name, s.Config)
+		}
+		return p.UpdateService(s.AppPath, s.Name, s.Config)
+	})
+	if err != nil {
+		return results, fmt.Errorf("winsvc.UpgradeProduct: %s: %v", prod.Name, err)
+	}
+	return results, nil
+}
+
+// RemoveProduct stops the services of prod, each once the services
+// depending on it are stopped, removes them and unregisters the shared
+// event source, which is kept if any service could not be removed.
+func (p *Manager) RemoveProduct(prod *Product) ([]Result, error) {
+	specs, err := prod.specs()
+	if err != nil {
+		return nil, err
+	}
+	results, err := runBatch(specs, true, func(s ServiceSpec) error {
+		installed, err := p.installed(s.Name)
+		if err != nil || !installed {
+			return err
+		}
+		state, err := p.Query(s.Name)
+		if err != nil {
+			return err
+		}
+		if state != "Stopped" {
+			if err := p.StopAndWait(s.Name, batchTimeout); err != nil {
+				return err
+			}
+		}
+		return p.remove(s.Name, false)
+	})
+	if err != nil {
+		return results, fmt.Errorf("winsvc.RemoveProduct: %s: %v", prod.Name, err)
+	}
+	if err := p.RemoveEventSource(prod.eventLog()); err != nil {
+		return results, fmt.Errorf("winsvc.RemoveProduct: %s: %v", prod.Name, err)
+	}
+	return results, nil
+}
+
+// installed reports whether service name is installed. Other errors
+// than the service not existing, such as access denied, are returned.
+func (p *Manager) installed(name string) (bool, error) {
+	s, err := p.openService(name, windows.SERVICE_QUERY_STATUS)
+	if err == windows.ERROR_SERVICE_DOES_NOT_EXIST {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("winsvc.QueryService: could not access service: %v", err)
+	}
+	s.Close()
+	return true, nil
+}
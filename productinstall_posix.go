@@ -0,0 +1,26 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+func InstallProduct(prod *Product) ([]Result, error) {
+	panic("winsvc: only support windows!")
+}
+func UpgradeProduct(prod *Product) ([]Result, error) {
+	panic("winsvc: only support windows!")
+}
+func RemoveProduct(prod *Product) ([]Result, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) InstallProduct(prod *Product) ([]Result, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) UpgradeProduct(prod *Product) ([]Result, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) RemoveProduct(prod *Product) ([]Result, error) {
+	panic("winsvc: only support windows!")
+}
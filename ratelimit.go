@@ -0,0 +1,112 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitLogger returns a Logger which passes the messages to l once
+// per window: a message logged again within the window, with the same
+// level and event id, is counted instead, and written once the window is
+// over with the count, such as "could not connect (repeated 1200 more
+// times in 1m0s)". If max is not zero, at most max different messages
+// are passed per window, and the others are dropped and counted. The
+// counts are written with the next message after the window, and by
+// Close.
+//
+// The limit is kept by the logger, so per service and per process: a
+// service restarted by the service control manager logs its messages
+// afresh with each run. WithCrashLoopBreaker bounds those runs.
+func RateLimitLogger(l Logger, window time.Duration, max int) Logger {
+	return &rateLimitLogger{l: l, window: window, max: max, repeats: map[logKey]int{}, now: time.Now}
+}
+
+type logKey struct {
+	level Level
+	eid   uint32
+	msg   string
+}
+
+type rateLimitLogger struct {
+	l      Logger
+	window time.Duration
+	max    int
+	now    func() time.Time
+
+	mu      sync.Mutex
+	start   time.Time
+	repeats map[logKey]int // repeats of the messages passed in this window
+	dropped int
+}
+
+func (p *rateLimitLogger) Log(level Level, eid uint32, msg string) error {
+	now := p.now()
+	p.mu.Lock()
+	var summary []logKey
+	if now.Sub(p.start) >= p.window {
+		summary = p.summary()
+		p.start = now
+	}
+	k := logKey{level, eid, msg}
+	n, seen := p.repeats[k]
+	pass := false
+	switch {
+	case seen:
+		p.repeats[k] = n + 1
+	case p.max > 0 && len(p.repeats) >= p.max:
+		p.dropped++
+	default:
+		p.repeats[k] = 0
+		pass = true
+	}
+	p.mu.Unlock()
+	err := p.write(summary)
+	if pass {
+		if e := p.l.Log(level, eid, msg); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// summary returns the counts of the window which is over, and starts a
+// new one. p.mu is held.
+func (p *rateLimitLogger) summary() []logKey {
+	var s []logKey
+	for k, n := range p.repeats {
+		if n > 0 {
+			s = append(s, logKey{k.level, k.eid, fmt.Sprintf("%s (repeated %d more times in %v)", k.msg, n, p.window)})
+		}
+	}
+	if p.dropped > 0 {
+		s = append(s, logKey{LevelWarning, 1, fmt.Sprintf("winsvc: %d log messages dropped by the rate limit in %v", p.dropped, p.window)})
+	}
+	p.repeats = map[logKey]int{}
+	p.dropped = 0
+	return s
+}
+
+func (p *rateLimitLogger) write(summary []logKey) (err error) {
+	for _, k := range summary {
+		if e := p.l.Log(k.level, k.eid, k.msg); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+func (p *rateLimitLogger) Close() error {
+	p.mu.Lock()
+	summary := p.summary()
+	p.mu.Unlock()
+	err := p.write(summary)
+	if e := p.l.Close(); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// memLogger records the messages logged.
+type memLogger struct {
+	msgs []string
+}
+
+func (p *memLogger) Log(level Level, eid uint32, msg string) error {
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *memLogger) Close() error { return nil }
+
+func TestRateLimitLogger(t *testing.T) {
+	type entry struct {
+		at  time.Duration // since the first message
+		msg string
+	}
+	for _, tt := range []struct {
+		name    string
+		max     int
+		entries []entry
+		want    []string // after Close
+	}{
+		{
+			name:    "distinct messages pass",
+			entries: []entry{{0, "a"}, {time.Second, "b"}},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "repeats are counted",
+			entries: []entry{{0, "a"}, {time.Second, "a"}, {2 * time.Second, "a"}},
+			want:    []string{"a", "a (repeated 2 more times in 1m0s)"},
+		},
+		{
+			name:    "counts are written after the window",
+			entries: []entry{{0, "a"}, {time.Second, "a"}, {time.Minute, "b"}},
+			want:    []string{"a", "a (repeated 1 more times in 1m0s)", "b"},
+		},
+		{
+			name:    "a message passes again in a new window",
+			entries: []entry{{0, "a"}, {time.Minute, "a"}},
+			want:    []string{"a", "a"},
+		},
+		{
+			name:    "messages over max are dropped",
+			max:     1,
+			entries: []entry{{0, "a"}, {time.Second, "b"}, {2 * time.Second, "c"}},
+			want:    []string{"a", "winsvc: 2 log messages dropped by the rate limit in 1m0s"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ml := &memLogger{}
+			start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			var now time.Time
+			l := RateLimitLogger(ml, time.Minute, tt.max).(*rateLimitLogger)
+			l.now = func() time.Time { return now }
+			for _, e := range tt.entries {
+				now = start.Add(e.at)
+				l.Log(LevelError, 1, e.msg)
+			}
+			l.Close()
+			if !reflect.DeepEqual(ml.msgs, tt.want) {
+				t.Errorf("logged %q, want %q", ml.msgs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+func ReloadService(name string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.Reload(name)
+}
+
+// Reload asks service name to reload its configuration, by sending it
+// the ControlReload control (see WithReload). It does not wait for the
+// reload to complete.
+func (p *Manager) Reload(name string) error {
+	s, err := p.openService(name, windows.SERVICE_USER_DEFINED_CONTROL)
+	if err != nil {
+		return fmt.Errorf("winsvc.ReloadService: could not access service: %v", err)
+	}
+	defer s.Close()
+	err = p.retry(func() error {
+		_, err := s.Control(svc.Cmd(ControlReload))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("winsvc.ReloadService: could not send reload control: %v", err)
+	}
+	return nil
+}
+
+// reload calls the reload callback, one call at a time, reporting the
+// outcome to the log.
+func (p *serviceRuntime) reload() error {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+	if err := p.opts.reload(); err != nil {
+		p.elog.Error(1, fmt.Sprintf("winsvc.Execute: reload failed: %v", err))
+		return err
+	}
+	p.elog.Info(1, "winsvc.Execute: configuration reloaded")
+	return nil
+}
+
+// paramChange passes the Parameters of the service to the ParamChange
+// callback, one call at a time.
+func (p *serviceRuntime) paramChange() {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+	params, err := GetParameters(p.serviceName())
+	if err != nil {
+		p.elog.Error(1, fmt.Sprintf("winsvc.Execute: could not read parameters: %v", err))
+		return
+	}
+	p.opts.paramChange(params)
+}
@@ -0,0 +1,60 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modshlwapi               = windows.NewLazySystemDLL("shlwapi.dll")
+	procSHLoadIndirectString = modshlwapi.NewProc("SHLoadIndirectStringW")
+)
+
+// LoadResourceString returns the text of the resource reference ref
+// (see ResourceString) in the language of the current user. It fails if
+// the module or the string resource does not exist, so it validates a
+// reference before it is installed.
+func LoadResourceString(ref string) (string, error) {
+	if !isResourceString(ref) {
+		return "", fmt.Errorf("winsvc.LoadResourceString: %q is not a resource reference", ref)
+	}
+	src, err := windows.UTF16PtrFromString(ref)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]uint16, 1024)
+	hr, _, _ := procSHLoadIndirectString.Call(
+		uintptr(unsafe.Pointer(src)),
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(len(buf)),
+		0,
+	)
+	if hr != 0 {
+		return "", fmt.Errorf("winsvc.LoadResourceString: could not load %s: %v", ref, windows.Errno(hr))
+	}
+	return windows.UTF16ToString(buf), nil
+}
+
+// validateResourceStrings checks the resource references of cfg. The
+// modules are looked up locally, so it does not apply to remote installs.
+func validateResourceStrings(cfg *ServiceConfig) error {
+	if !capabilities().Shell {
+		return nil // installed unchecked
+	}
+	for _, s := range []string{cfg.DisplayName, cfg.Description} {
+		if isResourceString(s) {
+			if _, err := LoadResourceString(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
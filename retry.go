@@ -0,0 +1,79 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// SetRetryPolicy sets how p retries calls which fail with a transient
+// error. A nil rp disables retries.
+func (p *Manager) SetRetryPolicy(rp *RetryPolicy) {
+	p.retryPolicy = rp
+}
+
+// isTransient reports whether err is worth retrying.
+func isTransient(err error) bool {
+	var errno windows.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case windows.RPC_S_SERVER_UNAVAILABLE,
+		windows.RPC_S_CALL_FAILED,
+		windows.ERROR_SERVICE_DATABASE_LOCKED,
+		windows.ERROR_SHARING_VIOLATION:
+		return true
+	}
+	return false
+}
+
+// retry calls fn until it succeeds, fails with an error which is not
+// transient, or the retry policy of p is exhausted.
+func (p *Manager) retry(fn func() error) error {
+	return retryPolicy(p.retryPolicy, fn)
+}
+
+// retryAction is retry for a call which is not idempotent, such as
+// starting a service: its reply may be lost once the call took effect,
+// so a retry which fails with done, the error of the call made twice,
+// succeeds.
+func (p *Manager) retryAction(done windows.Errno, fn func() error) error {
+	retried := false
+	return p.retry(func() error {
+		err := fn()
+		if retried && err == done {
+			return nil
+		}
+		retried = true
+		return err
+	})
+}
+
+func retryPolicy(rp *RetryPolicy, fn func() error) error {
+	for n := 0; ; n++ {
+		err := fn()
+		if err == nil || rp == nil || n+1 >= rp.MaxAttempts || !isTransient(err) {
+			return err
+		}
+		time.Sleep(rp.delay(n))
+	}
+}
+
+// openService opens service name with the access rights access only,
+// retrying transient errors.
+func (p *Manager) openService(name string, access uint32) (s *mgr.Service, err error) {
+	err = p.retry(func() error {
+		s, err = openService(p.m.Handle, name, access)
+		return err
+	})
+	return s, err
+}
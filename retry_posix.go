@@ -0,0 +1,11 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+func (p *Manager) SetRetryPolicy(rp *RetryPolicy) {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,37 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"time"
+)
+
+// RetryPolicy tells a Manager how to retry service control manager calls
+// which fail with a transient error: the remote RPC server being
+// unavailable, the service database being locked, or a sharing violation.
+type RetryPolicy struct {
+	MaxAttempts int           // attempts in total, including the first one
+	Backoff     time.Duration // delay before the first retry, doubled after each one
+	MaxBackoff  time.Duration // upper bound of the delay, if not zero
+}
+
+// DefaultRetryPolicy is the retry policy of new Managers.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	Backoff:     500 * time.Millisecond,
+	MaxBackoff:  8 * time.Second,
+}
+
+// delay returns the delay before retry n, counting from 0.
+func (rp *RetryPolicy) delay(n int) time.Duration {
+	d := rp.Backoff
+	for i := 0; i < n && (rp.MaxBackoff == 0 || d < rp.MaxBackoff); i++ {
+		d *= 2
+	}
+	if rp.MaxBackoff > 0 && d > rp.MaxBackoff {
+		d = rp.MaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,867 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/debug"
+)
+
+func RunAsService(name string, start, stop func(), isDebug bool, opts ...Option) (err error) {
+	p := &serviceRuntime{
+		name:  name,
+		start: func(context.Context, StatusReporter) { start() },
+		stop:  stop,
+		opts:  newOptions(append([]Option{WithDebug(isDebug)}, opts...)),
+	}
+	return p.run(p)
+}
+
+// RunAsServiceContext is like RunAsService, but start receives a context
+// which is canceled when the service is asked to stop or shut down.
+// The service stops once start returns, whether because it was asked to
+// stop or on its own.
+func RunAsServiceContext(name string, start func(ctx context.Context), isDebug bool, opts ...Option) (err error) {
+	p := &serviceRuntime{
+		name:  name,
+		start: func(ctx context.Context, _ StatusReporter) { start(ctx) },
+		opts:  newOptions(append([]Option{WithDebug(isDebug)}, opts...)),
+	}
+	return p.run(p)
+}
+
+// RunAsServiceWithStatus is like RunAsServiceContext, but the service stays
+// in StartPending until start calls status.Running, so lengthy
+// initialization can report its progress first. If start returns before
+// that, the service stops with an error; after that, it stops as for
+// RunAsServiceContext.
+func RunAsServiceWithStatus(name string, start func(ctx context.Context, status StatusReporter), isDebug bool, opts ...Option) (err error) {
+	p := &serviceRuntime{
+		name:          name,
+		start:         start,
+		reportRunning: true,
+		opts:          newOptions(append([]Option{WithDebug(isDebug)}, opts...)),
+	}
+	return p.run(p)
+}
+
+// RunHandler runs h as the service name, with the same event log setup,
+// debug mode switching and error reporting as RunAsService. It is meant
+// for programs which implement the svc.Handler state machine themselves,
+// so the Start/Stop related options have no effect.
+func RunHandler(name string, h svc.Handler, opts ...Option) error {
+	p := &serviceRuntime{name: name, opts: newOptions(opts)}
+	return p.run(&userHandler{p: p, h: h})
+}
+
+type userHandler struct {
+	p *serviceRuntime
+	h svc.Handler
+}
+
+func (p *userHandler) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
+	p.p.setServiceName(args)
+	return p.h.Execute(args, r, changes)
+}
+
+// RunAsServiceAsync is like RunAsService, but runs the service in the
+// background and returns immediately.
+func RunAsServiceAsync(name string, start, stop func(), isDebug bool, opts ...Option) *ServiceRuntime {
+	p := &serviceRuntime{
+		name:        name,
+		start:       func(context.Context, StatusReporter) { start() },
+		stop:        stop,
+		stopRequest: make(chan struct{}),
+		opts:        newOptions(append([]Option{WithDebug(isDebug)}, opts...)),
+	}
+	rt := &ServiceRuntime{p: p, stopped: make(chan struct{})}
+	go func() {
+		defer close(rt.stopped)
+		rt.err = p.run(p)
+	}()
+	return rt
+}
+
+// ServiceRuntime controls a service started by RunAsServiceAsync.
+type ServiceRuntime struct {
+	p       *serviceRuntime
+	stopped chan struct{}
+	err     error
+}
+
+// Stopped returns a channel which is closed once the service has stopped.
+func (rt *ServiceRuntime) Stopped() <-chan struct{} {
+	return rt.stopped
+}
+
+// Err returns the error the service stopped with, and nil while it runs.
+func (rt *ServiceRuntime) Err() error {
+	select {
+	case <-rt.stopped:
+		return rt.err
+	default:
+		return nil
+	}
+}
+
+// RequestStop asks the service to stop, as if the service control manager
+// sent a Stop request. It does not wait; use Stopped for that.
+func (rt *ServiceRuntime) RequestStop() {
+	rt.p.requestStop()
+}
+
+// Name returns the name the service control manager started the service
+// under (see ServiceName), or "" until it has started.
+func (rt *ServiceRuntime) Name() string {
+	return rt.p.startedName()
+}
+
+// Args returns the start arguments of the service (see ServiceArgs).
+func (rt *ServiceRuntime) Args() []string {
+	return rt.p.startArgs()
+}
+
+// Listeners returns the listeners of the service bound by WithListener
+// options, or nil until it has started.
+func (rt *ServiceRuntime) Listeners() []net.Listener {
+	return rt.p.boundListeners()
+}
+
+type runtimeKey struct{}
+
+// runtimeFrom returns the runtime of the service started with ctx.
+func runtimeFrom(ctx context.Context) *serviceRuntime {
+	p, _ := ctx.Value(runtimeKey{}).(*serviceRuntime)
+	return p
+}
+
+// ServiceName returns the name the service control manager started the
+// service with the start context ctx under, which differs from the name
+// given to RunAsService when one binary is installed under several
+// names. It returns "" for a context not given by winsvc.
+func ServiceName(ctx context.Context) string {
+	if p := runtimeFrom(ctx); p != nil {
+		return p.startedName()
+	}
+	return ""
+}
+
+// ServiceListeners returns the listeners of the service with the start
+// context ctx bound by WithListener options.
+func ServiceListeners(ctx context.Context) []net.Listener {
+	if p := runtimeFrom(ctx); p != nil {
+		return p.boundListeners()
+	}
+	return nil
+}
+
+// bindListeners binds the WithListener listeners.
+func (p *serviceRuntime) bindListeners() ([]net.Listener, error) {
+	var ls []net.Listener
+	for _, spec := range p.opts.listeners {
+		var l net.Listener
+		var err error
+		if spec.network == "pipe" {
+			l, err = ListenPipe(spec.address, "")
+		} else {
+			l, err = net.Listen(spec.network, spec.address)
+		}
+		if err != nil {
+			for _, l := range ls {
+				l.Close()
+			}
+			return nil, err
+		}
+		ls = append(ls, l)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.listeners = ls
+	return ls, nil
+}
+
+// ServiceArgs returns the start arguments of the service with the start
+// context ctx, such as given to "sc start name arg...", without the
+// service name. Under a DebugController they are the ones given to
+// NewDebugController.
+func ServiceArgs(ctx context.Context) []string {
+	if p := runtimeFrom(ctx); p != nil {
+		return p.startArgs()
+	}
+	return nil
+}
+
+// setServiceName records the name and start arguments from the Execute
+// args, which start with the name the service was started under.
+func (p *serviceRuntime) setServiceName(args []string) {
+	name := p.name
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.svcName = name
+	p.args = args
+}
+
+// serviceName returns the name the service was started under, or the
+// name given to RunAsService before it started.
+func (p *serviceRuntime) serviceName() string {
+	if name := p.startedName(); name != "" {
+		return name
+	}
+	return p.name
+}
+
+// startedName returns the name the service was started under, or "".
+func (p *serviceRuntime) startedName() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.svcName
+}
+
+func (p *serviceRuntime) startArgs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.args
+}
+
+func (p *serviceRuntime) boundListeners() []net.Listener {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.listeners
+}
+
+// serviceRuntime holds the state of one running service, so several
+// services (or a service and a test) can run in the same process.
+type serviceRuntime struct {
+	name          string // as given to RunAsService
+	svcName       string // as started by the service control manager
+	args          []string
+	listeners     []net.Listener // bound by WithListener
+	start         func(ctx context.Context, status StatusReporter)
+	stop          func() // nil if start waits for its context instead
+	reportRunning bool   // start reports Running itself
+	stopRequest   chan struct{}
+	stopOnce      sync.Once
+	drainRequest  chan struct{}
+	drainOnce     sync.Once
+	opts          *options
+	elog          levelLogger
+
+	changes    chan<- svc.Status
+	executed   chan struct{} // closed once Execute returns
+	mu         sync.Mutex
+	status     svc.Status // last status reported
+	started    time.Time
+	stateSince time.Time
+	failErr    error // why the service stopped itself, see fail
+	reloadMu   sync.Mutex
+
+	writeStats func(name string, s ServiceStats) error // nil writes the registry
+}
+
+// fail stops the service because of err, which sets the exit code.
+func (p *serviceRuntime) fail(err error) {
+	p.elog.Error(1, fmt.Sprintf("winsvc.Execute: service failed: %v", err))
+	p.mu.Lock()
+	if p.failErr == nil {
+		p.failErr = err
+	}
+	p.mu.Unlock()
+	p.requestStop()
+}
+
+func (p *serviceRuntime) failure() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failErr
+}
+
+// requestStop makes Execute stop the service as if asked by the service
+// control manager.
+func (p *serviceRuntime) requestStop() {
+	p.stopOnce.Do(func() {
+		close(p.stopRequest)
+	})
+}
+
+// report sends status to the service control manager and remembers it.
+func (p *serviceRuntime) report(status svc.Status) {
+	p.mu.Lock()
+	prev, since := p.status.State, p.stateSince
+	if status.State != prev {
+		p.stateSince = time.Now()
+	}
+	p.status = status
+	p.mu.Unlock()
+	if h := p.opts.hook; h != nil && status.State != prev {
+		from := "Stopped"
+		if prev != 0 {
+			from = stateString(prev)
+		}
+		h.StateChanged(from, stateString(status.State), time.Since(since))
+	}
+	// once Execute returns nothing reads changes any more: a late report,
+	// say from a start goroutine still running, is dropped
+	select {
+	case p.changes <- status:
+	case <-p.executed:
+	}
+}
+
+// currentStatus returns the last status reported.
+func (p *serviceRuntime) currentStatus() svc.Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.status
+}
+
+func (p *serviceRuntime) run(h svc.Handler) (err error) {
+	if p.stopRequest == nil {
+		p.stopRequest = make(chan struct{})
+	}
+	p.drainRequest = make(chan struct{})
+	isDebug := p.opts.debug
+	if logger := p.opts.logger; logger != nil {
+		p.elog = levelLogger{logger}
+	} else if logger = Defaults().Logger; logger != nil {
+		p.elog = levelLogger{logger}
+	} else {
+		var l Logger
+		if isDebug {
+			l = &debugLogger{debug.New(p.name)}
+		} else {
+			// opened with the first message, once StartPending is reported
+			l = &lazyLogger{open: func() (Logger, error) {
+				source := p.opts.eventSource
+				if source == "" {
+					// the instance name the service control manager
+					// started, known once Execute runs
+					source = installedEventSource(p.serviceName())
+				}
+				l, err := OpenEventLogger(source)
+				if err != nil && Defaults().Compatibility != CompatFull {
+					l, err = fallbackLogger(p.name)
+				}
+				return l, err
+			}}
+		}
+		rl := p.opts.logRateLimit
+		if rl == nil {
+			d := Defaults()
+			rl = &logRateLimit{window: d.LogRateWindow, max: d.LogRateMax}
+		}
+		if rl.window > 0 {
+			l = RateLimitLogger(l, rl.window, rl.max)
+		}
+		p.elog = levelLogger{FilterLogger(l, p.opts.logLevel)}
+		defer p.elog.Close()
+	}
+	if p.opts.jobLimits != nil {
+		if err := p.applyJobLimits(p.opts.jobLimits); err != nil {
+			p.elog.Error(1, fmt.Sprintf("winsvc.RunAsService: %v", err))
+			return err
+		}
+	}
+
+	run := svc.Run
+	if c := p.opts.debugController; c != nil {
+		run = c.run
+	} else if isDebug {
+		run = debug.Run
+	}
+
+	if err = run(p.name, h); err != nil {
+		p.elog.Error(1, fmt.Sprintf("%s service failed: %v", p.name, err))
+		return
+	}
+	p.elog.Info(1, fmt.Sprintf("winsvc.RunAsService: %s service stopped", p.name))
+	return
+}
+
+func (p *serviceRuntime) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
+	p.setServiceName(args)
+	p.changes = changes
+	p.executed = make(chan struct{})
+	defer close(p.executed)
+	p.started = time.Now()
+	p.stateSince = p.started
+	reason := "Exited"
+	// the start timeout of the service control manager runs until this
+	// first report: the setup which may take time, such as opening the
+	// event log, comes after it
+	p.report(svc.Status{State: svc.StartPending, WaitHint: waitHint(p.opts.startWaitHint)})
+	p.elog.Debug(1, "winsvc.Execute:"+"begin")
+	p.elog.Info(1, fmt.Sprintf("winsvc.RunAsService: starting %s service", p.name))
+	p.degrade()
+	p.applyShutdownLevel()
+	accepts := p.opts.accepts
+	if p.opts.sessionHelper != nil {
+		accepts |= AcceptSessionChange
+	}
+	if p.opts.paramChange != nil {
+		accepts |= AcceptParamChange
+	}
+	if p.opts.netBindChange != nil {
+		accepts |= AcceptNetBindChange
+	}
+	cmdsAccepted := svc.Accepted(accepts)
+	if h := p.opts.hook; h != nil {
+		defer func() { h.Stopped(reason, time.Since(p.started), errno) }()
+	}
+	if !p.opts.debug {
+		stats := p.recordStart()
+		defer func() { p.recordStop(reason, ssec, errno) }()
+		if failures := p.opts.crashLoop.failures(stats.RecentFailures, time.Now()); failures != nil {
+			p.elog.Error(1, "winsvc.Execute: "+crashLoopMessage(p.opts.crashLoop, failures))
+			reason = "CrashLoop"
+			return true, CrashLoopExitCode
+		}
+	}
+	if err := p.runChecks(); err != nil {
+		p.elog.Error(1, fmt.Sprintf("winsvc.Execute: %v", err))
+		reason = "CheckFailed"
+		return checkExitCode(err)
+	}
+	if len(p.opts.listeners) > 0 {
+		ls, err := p.bindListeners()
+		if err != nil {
+			p.elog.Error(1, fmt.Sprintf("winsvc.Execute: could not bind listener: %v", err))
+			reason = "InitFailed"
+			return exitCode(err)
+		}
+		defer func() {
+			for _, l := range ls {
+				l.Close()
+			}
+		}()
+	}
+	if p.opts.init != nil {
+		if err := p.runPending(p.opts.init); err != nil {
+			p.elog.Error(1, fmt.Sprintf("winsvc.Execute: initialization failed: %v", err))
+			reason = "InitFailed"
+			return exitCode(err)
+		}
+	}
+	status := &statusReporter{p: p, accepts: cmdsAccepted}
+	if !p.reportRunning {
+		status.Running()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, runtimeKey{}, p)
+	ctx = context.WithValue(ctx, stopTimeoutKey{}, p.opts.stopTimeout)
+	ctx = context.WithValue(ctx, failKey{}, p.fail)
+	ctx = context.WithValue(ctx, statusKey{}, StatusReporter(status))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.runStart(ctx, status)
+	}()
+
+	if p.opts.adminHTTP != nil {
+		srv, err := p.serveAdminHTTP()
+		if err != nil {
+			p.elog.Error(1, fmt.Sprintf("winsvc.Execute: could not serve admin endpoint: %v", err))
+		} else {
+			defer srv.close()
+		}
+	}
+	if p.opts.controlPipe != nil {
+		cp, err := p.serveControlPipe()
+		if err != nil {
+			p.elog.Error(1, fmt.Sprintf("winsvc.Execute: could not serve control pipe: %v", err))
+		} else {
+			defer cp.close()
+		}
+	}
+
+	var helper *sessionHelper
+	if p.opts.sessionHelper != nil {
+		helper = p.newSessionHelper()
+		helper.startAll()
+		defer helper.stop()
+	}
+
+	// exited is watched unless start returning is how it hands over to
+	// the stop callback of RunAsService
+	exited := done
+	if p.stop != nil && !p.reportRunning && p.opts.restart == nil {
+		exited = nil
+	}
+
+	// drained is closed once the drain callback returns
+	var drained chan struct{}
+	drainRequest := p.drainRequest
+
+loop:
+	for {
+		select {
+		case <-exited:
+			if !status.isRunning() {
+				p.elog.Error(1, "winsvc.Execute: service exited before reporting Running")
+				return false, uint32(windows.ERROR_PROCESS_ABORTED)
+			}
+			if p.opts.restart != nil {
+				p.elog.Error(1, fmt.Sprintf("winsvc.Execute: service exited after %d restarts", p.opts.restart.MaxRestarts))
+				if p.opts.crashDump {
+					p.writeCrashDump()
+				}
+				errno = uint32(windows.ERROR_PROCESS_ABORTED)
+				break loop
+			}
+			if p.stop == nil {
+				// start waits for its context: returning ends the service
+				break loop
+			}
+			exited = nil
+		case <-p.stopRequest:
+			reason = "Request"
+			break loop
+		case <-drainRequest:
+			drainRequest = nil
+			drained = p.drain(cmdsAccepted)
+		case <-drained:
+			drained = nil
+			p.elog.Info(1, "winsvc.Execute: drained")
+			if p.currentStatus().State == svc.PausePending {
+				p.report(svc.Status{State: svc.Paused, Accepts: cmdsAccepted})
+			}
+		case c := <-r:
+			if h := p.opts.hook; h != nil {
+				h.Control(controlString(c.Cmd))
+			}
+			p.elog.Log(p.opts.controlLogLevel, 1, fmt.Sprintf("winsvc.Execute: received %s control", controlString(c.Cmd)))
+			switch c.Cmd {
+			case svc.Interrogate:
+				if p.opts.health != nil {
+					p.reportHealth()
+					break
+				}
+				p.report(c.CurrentStatus)
+				if p.opts.interrogateOnce {
+					break
+				}
+				// testing deadlock from https://code.google.com/p/winsvc/issues/detail?id=4
+				time.Sleep(p.opts.interrogateDelay)
+				p.report(c.CurrentStatus)
+			case svc.Stop, svc.Shutdown, svc.PreShutdown:
+				reason = controlString(c.Cmd)
+				if c.Cmd == svc.Stop && p.opts.stopReason != nil {
+					if r, ok := takeStopReason(p.serviceName()); ok {
+						p.elog.Info(1, fmt.Sprintf("winsvc.Execute: %v", r))
+						p.opts.stopReason(r)
+					}
+				}
+				break loop
+			case svc.Pause:
+				p.report(svc.Status{State: svc.Paused, Accepts: cmdsAccepted})
+			case svc.Continue:
+				p.report(svc.Status{State: svc.Running, Accepts: cmdsAccepted})
+			case svc.SessionChange:
+				if helper != nil {
+					helper.sessionChange(c)
+				}
+			case svc.ParamChange:
+				if p.opts.paramChange != nil {
+					go p.paramChange()
+				}
+			case svc.NetBindAdd, svc.NetBindRemove, svc.NetBindEnable, svc.NetBindDisable:
+				if p.opts.netBindChange != nil {
+					p.opts.netBindChange(netBindChange(c.Cmd))
+				}
+			case svc.Cmd(ControlReload):
+				if p.opts.reload != nil {
+					go p.reload()
+				}
+			case svc.Cmd(ControlDrain):
+				if p.opts.drain != nil {
+					p.requestDrain()
+				}
+			case svc.PowerEvent:
+				// nothing to do, accepted only for notification
+			default:
+				if !p.opts.ignoreUnexpected {
+					p.elog.Error(1, fmt.Sprintf("winsvc.Execute: unexpected control request #%d", c.Cmd))
+				}
+			}
+		}
+	}
+	if err := p.failure(); err != nil {
+		reason = "Failed"
+		ssec, errno = exitCode(err)
+	}
+	p.report(svc.Status{State: svc.StopPending, WaitHint: waitHint(p.opts.stopWaitHint)})
+	cancel()
+	if !p.waitStop(done) {
+		p.elog.Error(1, fmt.Sprintf("winsvc.Execute: service did not stop within %v", p.opts.stopTimeout))
+		return false, uint32(windows.ERROR_TIMEOUT)
+	}
+
+	p.elog.Debug(1, "winsvc.Execute:"+"end")
+	return
+}
+
+// runStart runs the start function, and runs it again as the restart
+// policy allows when it returns before the service is asked to stop.
+func (p *serviceRuntime) runStart(ctx context.Context, status StatusReporter) {
+	rp := p.opts.restart
+	backoff := time.Duration(0)
+	if rp != nil {
+		backoff = rp.Backoff
+	}
+	for restarts := 0; ; restarts++ {
+		p.callStart(ctx, status)
+		if ctx.Err() != nil || rp == nil || restarts >= rp.MaxRestarts {
+			return
+		}
+		p.elog.Warning(1, fmt.Sprintf("winsvc.Execute: service exited unexpectedly, restart %d of %d in %v", restarts+1, rp.MaxRestarts, backoff))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if rp.MaxBackoff > 0 && backoff > rp.MaxBackoff {
+			backoff = rp.MaxBackoff
+		}
+	}
+}
+
+// waitStop runs the stop callback, or waits for start to return, and
+// reports false if that takes longer than the configured stop timeout.
+// While waiting it keeps telling the service control manager that the
+// stop is making progress.
+func (p *serviceRuntime) waitStop(done <-chan struct{}) bool {
+	stopped := done
+	if p.stop != nil {
+		ch := make(chan struct{})
+		go func() {
+			defer close(ch)
+			p.stop()
+		}()
+		stopped = ch
+	}
+	var timeout <-chan time.Time
+	if p.opts.stopTimeout > 0 {
+		timeout = time.After(p.opts.stopTimeout)
+	}
+	var tick <-chan time.Time
+	if p.opts.stopWaitHint > 0 {
+		t := time.NewTicker(p.opts.stopWaitHint / 2)
+		defer t.Stop()
+		tick = t.C
+	}
+	var checkPoint uint32
+	for {
+		select {
+		case <-stopped:
+			return true
+		case <-timeout:
+			return false
+		case <-tick:
+			checkPoint++
+			p.report(svc.Status{
+				State:      svc.StopPending,
+				CheckPoint: checkPoint,
+				WaitHint:   waitHint(p.opts.stopWaitHint),
+			})
+		}
+	}
+}
+
+// runChecks runs the pre-start checks, stopping at the first failure.
+func (p *serviceRuntime) runChecks() error {
+	for _, c := range p.opts.checks {
+		if err := p.runPending(c.check); err != nil {
+			return &CheckError{Name: c.name, Err: err}
+		}
+	}
+	return nil
+}
+
+// checkExitCode returns the exit code reported for the failed check err,
+// as exitCode, with CheckFailedExitCode for errors without a code.
+func checkExitCode(err error) (ssec bool, errno uint32) {
+	var ee *ExitError
+	var en windows.Errno
+	if errors.As(err, &ee) || errors.As(err, &en) {
+		return exitCode(err)
+	}
+	return true, CheckFailedExitCode
+}
+
+// runPending calls fn, reporting StartPending checkpoints
+// until it returns.
+func (p *serviceRuntime) runPending(fn func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	var checkPoint uint32
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-tick.C:
+			checkPoint++
+			p.report(svc.Status{State: svc.StartPending, CheckPoint: checkPoint, WaitHint: waitHint(3 * time.Second)})
+		}
+	}
+}
+
+// exitCode returns the exit code reported for err: the codes of an
+// *ExitError, a Win32 error code, or else service specific exit code 1.
+func exitCode(err error) (ssec bool, errno uint32) {
+	var ee *ExitError
+	if errors.As(err, &ee) {
+		if ee.Win32ExitCode == errorServiceSpecificError {
+			return true, ee.ServiceSpecificExitCode
+		}
+		return false, ee.Win32ExitCode
+	}
+	var en windows.Errno
+	if errors.As(err, &en) {
+		return false, uint32(en)
+	}
+	return true, 1
+}
+
+// reportHealth runs the health check and reports the current status with
+// its outcome as exit code.
+func (p *serviceRuntime) reportHealth() {
+	err := p.opts.health()
+	p.mu.Lock()
+	status := p.status
+	p.mu.Unlock()
+	status.Win32ExitCode, status.ServiceSpecificExitCode = 0, 0
+	if err != nil {
+		p.elog.Warning(1, fmt.Sprintf("winsvc.Execute: health check failed: %v", err))
+		ssec, errno := exitCode(err)
+		if ssec {
+			status.Win32ExitCode, status.ServiceSpecificExitCode = errorServiceSpecificError, errno
+		} else {
+			status.Win32ExitCode = errno
+		}
+	}
+	p.report(status)
+}
+
+func netBindChange(c svc.Cmd) NetBindChange {
+	switch c {
+	case svc.NetBindRemove:
+		return NetBindRemove
+	case svc.NetBindEnable:
+		return NetBindEnable
+	case svc.NetBindDisable:
+		return NetBindDisable
+	}
+	return NetBindAdd
+}
+
+func controlString(c svc.Cmd) string {
+	switch c {
+	case svc.Stop:
+		return "Stop"
+	case svc.Pause:
+		return "Pause"
+	case svc.Continue:
+		return "Continue"
+	case svc.Interrogate:
+		return "Interrogate"
+	case svc.Shutdown:
+		return "Shutdown"
+	case svc.ParamChange:
+		return "ParamChange"
+	case svc.NetBindAdd:
+		return "NetBindAdd"
+	case svc.NetBindRemove:
+		return "NetBindRemove"
+	case svc.NetBindEnable:
+		return "NetBindEnable"
+	case svc.NetBindDisable:
+		return "NetBindDisable"
+	case svc.DeviceEvent:
+		return "DeviceEvent"
+	case svc.HardwareProfileChange:
+		return "HardwareProfileChange"
+	case svc.PowerEvent:
+		return "PowerEvent"
+	case svc.SessionChange:
+		return "SessionChange"
+	case svc.PreShutdown:
+		return "PreShutdown"
+	case svc.Cmd(ControlReload):
+		return "Reload"
+	case svc.Cmd(ControlDrain):
+		return "Drain"
+	}
+	return fmt.Sprintf("Control(%d)", c)
+}
+
+func waitHint(d time.Duration) uint32 {
+	return uint32(d / time.Millisecond)
+}
+
+type statusReporter struct {
+	mu         sync.Mutex
+	p          *serviceRuntime
+	accepts    svc.Accepted
+	checkPoint uint32
+	running    bool
+}
+
+func (p *statusReporter) Pending(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return
+	}
+	p.checkPoint++
+	p.p.report(svc.Status{
+		State:      svc.StartPending,
+		CheckPoint: p.checkPoint,
+		WaitHint:   waitHint(d),
+	})
+}
+
+func (p *statusReporter) Running() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return
+	}
+	p.running = true
+	if p.p.opts.tuning != nil {
+		p.p.applyTuning()
+	}
+	if p.p.opts.keepPrivileges != nil {
+		if err := KeepPrivileges(p.p.opts.keepPrivileges...); err != nil {
+			p.p.elog.Error(1, fmt.Sprintf("winsvc.Execute: %v", err))
+			p.p.requestStop()
+		}
+	}
+	p.p.report(svc.Status{State: svc.Running, Accepts: p.accepts})
+}
+
+func (p *statusReporter) isRunning() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running
+}
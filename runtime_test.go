@@ -0,0 +1,268 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+func newTestRuntime(name string, changes chan svc.Status) *serviceRuntime {
+	return &serviceRuntime{
+		name:    name,
+		opts:    newOptions(nil),
+		elog:    levelLogger{NewWriterLogger(ioutil.Discard)},
+		changes: changes,
+	}
+}
+
+// Two runtimes in one process keep their own state.
+func TestRuntimeStateIsolated(t *testing.T) {
+	var wg sync.WaitGroup
+	runtimes := make([]*serviceRuntime, 2)
+	for i, state := range []svc.State{svc.Running, svc.Paused} {
+		changes := make(chan svc.Status)
+		p := newTestRuntime("svc", changes)
+		runtimes[i] = p
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range changes {
+			}
+		}()
+		go func(state svc.State) {
+			defer wg.Done()
+			defer close(changes)
+			for j := 0; j < 100; j++ {
+				p.report(svc.Status{State: svc.StartPending, CheckPoint: uint32(j)})
+				p.currentStatus()
+			}
+			p.report(svc.Status{State: state})
+		}(state)
+	}
+	wg.Wait()
+	if got := runtimes[0].currentStatus().State; got != svc.Running {
+		t.Errorf("first runtime is in state %d, want Running", got)
+	}
+	if got := runtimes[1].currentStatus().State; got != svc.Paused {
+		t.Errorf("second runtime is in state %d, want Paused", got)
+	}
+}
+
+// Two services started in one process see their own name and arguments.
+func TestRuntimeNameIsolated(t *testing.T) {
+	a, b := newTestRuntime("svc", nil), newTestRuntime("svc", nil)
+	a.setServiceName([]string{"svc$a", "-x"})
+	b.setServiceName([]string{"svc$b"})
+	actx := context.WithValue(context.Background(), runtimeKey{}, a)
+	bctx := context.WithValue(context.Background(), runtimeKey{}, b)
+	if got := ServiceName(actx); got != "svc$a" {
+		t.Errorf("first service name is %q, want svc$a", got)
+	}
+	if got := ServiceName(bctx); got != "svc$b" {
+		t.Errorf("second service name is %q, want svc$b", got)
+	}
+	if got := ServiceArgs(actx); len(got) != 1 || got[0] != "-x" {
+		t.Errorf("first service args are %q, want [-x]", got)
+	}
+	if got := ServiceArgs(bctx); len(got) != 0 {
+		t.Errorf("second service args are %q, want none", got)
+	}
+	if got := ServiceName(context.Background()); got != "" {
+		t.Errorf("name outside a service is %q, want empty", got)
+	}
+}
+
+// The start goroutine, the health reporter and Execute report at once.
+func TestStatusReporterConcurrent(t *testing.T) {
+	changes := make(chan svc.Status)
+	p := newTestRuntime("svc", changes)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range changes {
+		}
+	}()
+	status := &statusReporter{p: p, accepts: svc.AcceptStop}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				status.Pending(0)
+				p.currentStatus()
+			}
+			status.Running()
+		}()
+	}
+	wg.Wait()
+	close(changes)
+	<-done
+	if got := p.currentStatus().State; got != svc.Running {
+		t.Fatalf("state is %d, want Running", got)
+	}
+}
+
+// A report after Execute returned, when nothing reads changes, does not
+// block.
+func TestReportAfterExecute(t *testing.T) {
+	p := newTestRuntime("svc", make(chan svc.Status))
+	p.executed = make(chan struct{})
+	close(p.executed)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.report(svc.Status{State: svc.Running})
+		s := &statusReporter{p: p}
+		s.Pending(time.Second)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("report blocked after Execute returned")
+	}
+}
+
+// levelRecorder records the levels of the messages logged.
+type levelRecorder struct {
+	mu     sync.Mutex
+	levels []Level
+}
+
+func (p *levelRecorder) Log(level Level, eid uint32, msg string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.levels = append(p.levels, level)
+	return nil
+}
+
+func (p *levelRecorder) Close() error { return nil }
+
+// A service whose account cannot write its stats says so at a level
+// logged by default.
+func TestRecordStatsWriteFailure(t *testing.T) {
+	p := newTestRuntime("winsvc-test-no-such-service", nil)
+	rec := &levelRecorder{}
+	p.elog = levelLogger{rec}
+	p.writeStats = func(name string, s ServiceStats) error {
+		return errors.New("Access is denied.")
+	}
+	p.recordStart()
+	p.recordStop("Failed", true, 1)
+	if len(rec.levels) != 2 {
+		t.Fatalf("logged %d messages, want 2", len(rec.levels))
+	}
+	for _, l := range rec.levels {
+		if l < LevelWarning {
+			t.Errorf("stats write failure logged at %v, want warning or above", l)
+		}
+	}
+}
+
+// A context start function which returns on its own stops the service
+// instead of leaving it Running.
+func TestContextStartReturns(t *testing.T) {
+	for _, reportRunning := range []bool{false, true} {
+		p := &serviceRuntime{
+			name: "svc",
+			start: func(ctx context.Context, status StatusReporter) {
+				status.Running()
+			},
+			reportRunning: reportRunning,
+			opts:          newOptions([]Option{WithDebug(true)}),
+			elog:          levelLogger{NewWriterLogger(ioutil.Discard)},
+			stopRequest:   make(chan struct{}),
+			drainRequest:  make(chan struct{}),
+		}
+		changes := make(chan svc.Status)
+		done := make(chan uint32)
+		go func() {
+			_, errno := p.Execute([]string{"svc"}, make(chan svc.ChangeRequest), changes)
+			done <- errno
+		}()
+	wait:
+		for {
+			select {
+			case <-changes:
+			case errno := <-done:
+				if errno != 0 {
+					t.Errorf("reportRunning %v: exit code %d, want 0", reportRunning, errno)
+				}
+				break wait
+			case <-time.After(5 * time.Second):
+				t.Fatalf("reportRunning %v: service still running after start returned", reportRunning)
+			}
+		}
+	}
+}
+
+// slowOpen is how long the event log takes to open in the benchmarks,
+// as on a machine whose event log service is slow to respond.
+const slowOpen = 20 * time.Millisecond
+
+// benchmarkStartPending measures how long Execute takes to report its
+// first StartPending, the report the start timeout of the service
+// control manager waits for, with the logger opened before Execute as
+// the runtime used to, or lazily as it does now.
+func benchmarkStartPending(b *testing.B, eager bool) {
+	open := func() (Logger, error) {
+		time.Sleep(slowOpen)
+		return NewWriterLogger(ioutil.Discard), nil
+	}
+	for i := 0; i < b.N; i++ {
+		p := &serviceRuntime{
+			name:         "bench",
+			start:        func(ctx context.Context, _ StatusReporter) { <-ctx.Done() },
+			opts:         newOptions([]Option{WithDebug(true)}),
+			stopRequest:  make(chan struct{}),
+			drainRequest: make(chan struct{}),
+		}
+		var l Logger = &lazyLogger{open: open}
+		if eager {
+			l, _ = open()
+		}
+		p.elog = levelLogger{FilterLogger(l, p.opts.logLevel)}
+		changes := make(chan svc.Status)
+		r := make(chan svc.ChangeRequest)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			p.Execute([]string{"bench"}, r, changes)
+		}()
+		if s := <-changes; s.State != svc.StartPending {
+			b.Fatalf("first report is state %d, want StartPending", s.State)
+		}
+		b.StopTimer()
+		stop := r
+		for running := true; running; {
+			select {
+			case <-changes:
+			case stop <- svc.ChangeRequest{Cmd: svc.Stop}:
+				stop = nil
+			case <-done:
+				running = false
+			}
+		}
+		p.elog.Close()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkStartPendingEagerLog(b *testing.B) {
+	benchmarkStartPending(b, true)
+}
+
+func BenchmarkStartPendingLazyLog(b *testing.B) {
+	benchmarkStartPending(b, false)
+}
@@ -0,0 +1,27 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"time"
+)
+
+// SCM is the set of service control manager operations of a Manager.
+// Code written against SCM can be unit tested on any OS with a fake,
+// such as the one of package winsvctest.
+type SCM interface {
+	InstallWithConfig(appPath, name string, cfg ServiceConfig) error
+	Remove(name string) error
+	Start(name string) error
+	Stop(name string) error
+	StartAndWait(name string, timeout time.Duration) error
+	StopAndWait(name string, timeout time.Duration) error
+	Query(name string) (status string, err error)
+	GetServiceConfig(name string) (ServiceConfig, error)
+	SetStartType(name string, t StartType) error
+	Disconnect() error
+}
+
+var _ SCM = (*Manager)(nil)
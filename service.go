@@ -158,11 +158,7 @@ import (
 	"path/filepath"
 	"time"
 
-	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/svc"
-	"golang.org/x/sys/windows/svc/debug"
-	"golang.org/x/sys/windows/svc/eventlog"
-	"golang.org/x/sys/windows/svc/mgr"
 )
 
 func GetAppPath() (string, error) {
@@ -174,7 +170,7 @@ func GetAppPath() (string, error) {
 	fi, err := os.Stat(p)
 	if err == nil {
 		if !fi.Mode().IsDir() {
-			return p, nil
+			return servicePath(p), nil
 		}
 		err = fmt.Errorf("winsvc.GetAppPath: %s is directory", p)
 	}
@@ -183,7 +179,7 @@ func GetAppPath() (string, error) {
 		fi, err := os.Stat(p)
 		if err == nil {
 			if !fi.Mode().IsDir() {
-				return p, nil
+				return servicePath(p), nil
 			}
 			err = fmt.Errorf("winsvc.GetAppPath: %s is directory", p)
 		}
@@ -208,211 +204,82 @@ func IsAnInteractiveSession() bool {
 }
 
 func InstallService(appPath, name, desc string, params ...string) error {
-	m, err := mgr.Connect()
+	m, err := Connect()
 	if err != nil {
 		return err
 	}
 	defer m.Disconnect()
-	s, err := m.OpenService(name)
-	if err == nil {
-		s.Close()
-		return fmt.Errorf("winsvc.InstallService: service %s already exists", name)
-	}
-	s, err = m.CreateService(name, appPath,
-		mgr.Config{
-			DisplayName: desc,
-			StartType:   windows.SERVICE_AUTO_START,
-		},
-		params...,
-	)
-	if err != nil {
-		return err
-	}
-	defer s.Close()
-	err = eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info)
-	if err != nil {
-		s.Delete()
-		return fmt.Errorf("winsvc.InstallService: InstallAsEventCreate failed, err = %v", err)
-	}
-	return nil
+	return m.Install(appPath, name, desc, params...)
 }
 
 func RemoveService(name string) error {
-	m, err := mgr.Connect()
+	m, err := Connect()
 	if err != nil {
 		return err
 	}
 	defer m.Disconnect()
-	s, err := m.OpenService(name)
-	if err != nil {
-		return fmt.Errorf("winsvc.RemoveService: service %s is not installed", name)
-	}
-	defer s.Close()
-	err = s.Delete()
-	if err != nil {
-		return err
-	}
-	err = eventlog.Remove(name)
-	if err != nil {
-		return fmt.Errorf("winsvc.RemoveService: eventlog.Remove failed: %v", err)
-	}
-	return nil
+	return m.Remove(name)
 }
 
 func StartService(name string) error {
-	m, err := mgr.Connect()
+	m, err := Connect()
 	if err != nil {
 		return err
 	}
 	defer m.Disconnect()
-	s, err := m.OpenService(name)
-	if err != nil {
-		return fmt.Errorf("winsvc.StartService: could not access service: %v", err)
-	}
-	defer s.Close()
-	err = s.Start("p1", "p2", "p3")
-	if err != nil {
-		return fmt.Errorf("winsvc.StartService: could not start service: %v", err)
-	}
-	return nil
+	return m.Start(name)
 }
 
 func StopService(name string) error {
-	if err := controlService(name, svc.Stop, svc.Stopped); err != nil {
+	m, err := Connect()
+	if err != nil {
 		return err
 	}
-	return nil
+	defer m.Disconnect()
+	return m.Stop(name)
 }
 
-func QueryService(name string) (status string, err error) {
-	m, err := mgr.Connect()
+func StopServiceTimeout(name string, timeout time.Duration) error {
+	m, err := Connect()
 	if err != nil {
-		return
+		return err
 	}
 	defer m.Disconnect()
-	s, err := m.OpenService(name)
-	if err != nil {
-		err = fmt.Errorf("winsvc.QueryService: could not access service: %v", err)
-		return
-	}
-	defer s.Close()
+	return m.StopTimeout(name, timeout)
+}
 
-	statusCode, err := s.Query()
+func StartServiceAndWait(name string, timeout time.Duration) error {
+	m, err := Connect()
 	if err != nil {
-		return
-	}
-	switch statusCode.State {
-	case svc.Stopped:
-		return "Stopped", nil
-	case svc.StartPending:
-		return "StartPending", nil
-	case svc.StopPending:
-		return "StopPending", nil
-	case svc.Running:
-		return "Running", nil
-	case svc.ContinuePending:
-		return "ContinuePending", nil
-	case svc.PausePending:
-		return "PausePending", nil
-	case svc.Paused:
-		return "Paused", nil
+		return err
 	}
-	panic("unreached")
+	defer m.Disconnect()
+	return m.StartAndWait(name, timeout)
 }
 
-func controlService(name string, c svc.Cmd, to svc.State) error {
-	m, err := mgr.Connect()
+func StopServiceAndWait(name string, timeout time.Duration) error {
+	m, err := Connect()
 	if err != nil {
 		return err
 	}
 	defer m.Disconnect()
-	s, err := m.OpenService(name)
-	if err != nil {
-		return fmt.Errorf("winsvc.controlService: could not access service: %v", err)
-	}
-	defer s.Close()
-	status, err := s.Control(c)
-	if err != nil {
-		return fmt.Errorf("winsvc.controlService: could not send control=%d: %v", c, err)
-	}
-	timeout := time.Now().Add(10 * time.Second)
-	for status.State != to {
-		if timeout.Before(time.Now()) {
-			return fmt.Errorf("winsvc.controlService: timeout waiting for service to go to state=%d", to)
-		}
-		time.Sleep(300 * time.Millisecond)
-		status, err = s.Query()
-		if err != nil {
-			return fmt.Errorf("winsvc.controlService: could not retrieve service status: %v", err)
-		}
-	}
-	return nil
+	return m.StopAndWait(name, timeout)
 }
 
-var elog debug.Log
-
-func RunAsService(name string, start, stop func(), isDebug bool) (err error) {
-	if isDebug {
-		elog = debug.New(name)
-	} else {
-		elog, err = eventlog.Open(name)
-		if err != nil {
-			return
-		}
-	}
-	defer elog.Close()
-
-	run := svc.Run
-	if isDebug {
-		run = debug.Run
-	}
-
-	elog.Info(1, fmt.Sprintf("winsvc.RunAsService: starting %s service", name))
-	if err = run(name, &winService{Start: start, Stop: stop}); err != nil {
-		elog.Error(1, fmt.Sprintf("%s service failed: %v", name, err))
-		return
+func QueryServiceStatus(name string) (ServiceStatus, error) {
+	m, err := Connect()
+	if err != nil {
+		return ServiceStatus{}, err
 	}
-	elog.Info(1, fmt.Sprintf("winsvc.RunAsService: %s service stopped", name))
-	return
-}
-
-type winService struct {
-	Start func()
-	Stop  func()
+	defer m.Disconnect()
+	return m.QueryStatus(name)
 }
 
-func (p *winService) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
-	elog.Info(1, "winsvc.Execute:"+"begin")
-	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPauseAndContinue
-	changes <- svc.Status{State: svc.StartPending}
-	changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
-
-	go p.Start()
-
-loop:
-	for {
-		select {
-		case c := <-r:
-			switch c.Cmd {
-			case svc.Interrogate:
-				changes <- c.CurrentStatus
-				// testing deadlock from https://code.google.com/p/winsvc/issues/detail?id=4
-				time.Sleep(100 * time.Millisecond)
-				changes <- c.CurrentStatus
-			case svc.Stop, svc.Shutdown:
-				break loop
-			case svc.Pause:
-				changes <- svc.Status{State: svc.Paused, Accepts: cmdsAccepted}
-			case svc.Continue:
-				changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
-			default:
-				elog.Error(1, fmt.Sprintf("winsvc.Execute:: unexpected control request #%d", c))
-			}
-		}
+func QueryService(name string) (status string, err error) {
+	m, err := Connect()
+	if err != nil {
+		return
 	}
-	changes <- svc.Status{State: svc.StopPending}
-	p.Stop()
-
-	elog.Info(1, "winsvc.Execute:"+"end")
-	return
+	defer m.Disconnect()
+	return m.Query(name)
 }
@@ -7,9 +7,12 @@
 package winsvc
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 func GetAppPath() (string, error) {
@@ -50,7 +53,25 @@ func InstallService(appPath, name, desc string, params ...string) error {
 func RemoveService(name string) error {
 	panic("winsvc: only support windows!")
 }
-func RunAsService(name string, start, stop func(), isDebug bool) (err error) {
+func RunAsService(name string, start, stop func(), isDebug bool, opts ...Option) (err error) {
+	panic("winsvc: only support windows!")
+}
+func RunAsServiceContext(name string, start func(ctx context.Context), isDebug bool, opts ...Option) (err error) {
+	panic("winsvc: only support windows!")
+}
+func RunAsServiceWithStatus(name string, start func(ctx context.Context, status StatusReporter), isDebug bool, opts ...Option) (err error) {
+	panic("winsvc: only support windows!")
+}
+func RunAsServiceAsync(name string, start, stop func(), isDebug bool, opts ...Option) *ServiceRuntime {
+	panic("winsvc: only support windows!")
+}
+func ServiceListeners(ctx context.Context) []net.Listener {
+	panic("winsvc: only support windows!")
+}
+func ServiceName(ctx context.Context) string {
+	panic("winsvc: only support windows!")
+}
+func ServiceArgs(ctx context.Context) []string {
 	panic("winsvc: only support windows!")
 }
 func StartService(name string) error {
@@ -59,6 +80,39 @@ func StartService(name string) error {
 func StopService(name string) error {
 	panic("winsvc: only support windows!")
 }
+func StopServiceTimeout(name string, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
+func StartServiceAndWait(name string, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
+func StopServiceAndWait(name string, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
+func QueryServiceStatus(name string) (ServiceStatus, error) {
+	panic("winsvc: only support windows!")
+}
 func QueryService(name string) (status string, err error) {
 	panic("winsvc: only support windows!")
 }
+
+type ServiceRuntime struct{}
+
+func (rt *ServiceRuntime) Stopped() <-chan struct{} {
+	panic("winsvc: only support windows!")
+}
+func (rt *ServiceRuntime) Err() error {
+	panic("winsvc: only support windows!")
+}
+func (rt *ServiceRuntime) RequestStop() {
+	panic("winsvc: only support windows!")
+}
+func (rt *ServiceRuntime) Name() string {
+	panic("winsvc: only support windows!")
+}
+func (rt *ServiceRuntime) Args() []string {
+	panic("winsvc: only support windows!")
+}
+func (rt *ServiceRuntime) Listeners() []net.Listener {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,33 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+// ServiceRights are access rights to a service, granted to accounts by
+// its security descriptor (see GrantServiceAccess). The values match the
+// SERVICE_* access rights of Windows.
+type ServiceRights uint32
+
+const (
+	ServiceQueryConfig         ServiceRights = 0x0001
+	ServiceChangeConfig        ServiceRights = 0x0002
+	ServiceQueryStatus         ServiceRights = 0x0004
+	ServiceEnumerateDependents ServiceRights = 0x0008
+	ServiceStart               ServiceRights = 0x0010
+	ServiceStop                ServiceRights = 0x0020
+	ServicePauseContinue       ServiceRights = 0x0040
+	ServiceInterrogate         ServiceRights = 0x0080
+	ServiceUserDefinedControl  ServiceRights = 0x0100
+	ServiceReadControl         ServiceRights = 0x00020000 // read the security descriptor
+
+	// ServiceQuery lets an operator see the service, as "sc qc" and
+	// "sc query" or Query do.
+	ServiceQuery = ServiceQueryConfig | ServiceQueryStatus | ServiceEnumerateDependents |
+		ServiceInterrogate | ServiceReadControl
+
+	// ServiceOperate lets an operator also start, stop, pause and reload
+	// the service, but not reconfigure it.
+	ServiceOperate = ServiceQuery | ServiceStart | ServiceStop | ServicePauseContinue |
+		ServiceUserDefinedControl
+)
@@ -0,0 +1,190 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func GetServiceConfig(name string) (ServiceConfig, error) {
+	m, err := Connect()
+	if err != nil {
+		return ServiceConfig{}, err
+	}
+	defer m.Disconnect()
+	return m.GetServiceConfig(name)
+}
+
+// GetServiceConfig returns the effective configuration of service name,
+// including its failure actions and triggers.
+func (p *Manager) GetServiceConfig(name string) (ServiceConfig, error) {
+	s, err := p.openService(name, windows.SERVICE_QUERY_CONFIG)
+	if err != nil {
+		return ServiceConfig{}, fmt.Errorf("winsvc.GetServiceConfig: could not access service: %v", err)
+	}
+	defer s.Close()
+	cfg, err := readServiceConfig(s)
+	if err != nil {
+		return ServiceConfig{}, fmt.Errorf("winsvc.GetServiceConfig: %v", err)
+	}
+	return cfg, nil
+}
+
+func DependentServices(name string) ([]string, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.DependentServices(name)
+}
+
+// DependentServices returns the services which depend on service name,
+// directly or through other services, in the order they are stopped.
+func (p *Manager) DependentServices(name string) ([]string, error) {
+	s, err := p.openService(name, windows.SERVICE_ENUMERATE_DEPENDENTS)
+	if err != nil {
+		return nil, fmt.Errorf("winsvc.DependentServices: could not access service: %v", err)
+	}
+	defer s.Close()
+	names, err := s.ListDependentServices(svc.AnyActivity)
+	if err != nil {
+		return nil, fmt.Errorf("winsvc.DependentServices: %v", err)
+	}
+	return names, nil
+}
+
+func DiffConfig(name string, desired ServiceConfig) ([]Change, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	return m.DiffConfig(name, desired)
+}
+
+// DiffConfig reports how the live configuration of service name differs
+// from desired. No changes means the service is configured as desired.
+func (p *Manager) DiffConfig(name string, desired ServiceConfig) ([]Change, error) {
+	current, err := p.GetServiceConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	return diffConfig(current, desired), nil
+}
+
+func readServiceConfig(s *mgr.Service) (ServiceConfig, error) {
+	c, err := s.Config()
+	if err != nil {
+		return ServiceConfig{}, err
+	}
+	cfg := fromMgrConfig(c)
+	if cfg.Recovery, err = readRecovery(s); err != nil {
+		return ServiceConfig{}, err
+	}
+	if cfg.Triggers, err = queryTriggers(s.Handle); err != nil {
+		return ServiceConfig{}, err
+	}
+	if cfg.PreshutdownTimeout, err = queryPreshutdownTimeout(s.Handle); err != nil {
+		return ServiceConfig{}, err
+	}
+	return cfg, nil
+}
+
+func fromMgrConfig(c mgr.Config) ServiceConfig {
+	cfg := ServiceConfig{
+		DisplayName:    c.DisplayName,
+		Description:    c.Description,
+		ServiceType:    ServiceType(c.ServiceType &^ windows.SERVICE_INTERACTIVE_PROCESS),
+		Interactive:    c.ServiceType&windows.SERVICE_INTERACTIVE_PROCESS != 0,
+		ErrorControl:   ErrorControl(c.ErrorControl),
+		LoadOrderGroup: c.LoadOrderGroup,
+		TagId:          c.TagId,
+		Dependencies:   c.Dependencies,
+		Account:        c.ServiceStartName,
+		SidType:        SidType(c.SidType),
+	}
+	switch c.StartType {
+	case windows.SERVICE_DEMAND_START:
+		cfg.StartType = StartTypeManual
+	case windows.SERVICE_DISABLED:
+		cfg.StartType = StartTypeDisabled
+	default:
+		cfg.StartType = StartTypeAutomatic
+		if c.DelayedAutoStart {
+			cfg.StartType = StartTypeAutomaticDelayed
+		}
+	}
+	if args, err := windows.DecomposeCommandLine(c.BinaryPathName); err == nil && len(args) > 0 {
+		cfg.BinaryPath, cfg.Args = args[0], args[1:]
+	} else {
+		cfg.BinaryPath = c.BinaryPathName
+	}
+	if len(cfg.Args) == 0 {
+		cfg.Args = nil
+	}
+	if len(cfg.Dependencies) == 0 {
+		cfg.Dependencies = nil
+	}
+	return cfg
+}
+
+func readRecovery(s *mgr.Service) (*RecoveryConfig, error) {
+	actions, err := s.RecoveryActions()
+	if err != nil {
+		return nil, err
+	}
+	if len(actions) == 0 {
+		return nil, nil
+	}
+	rc := &RecoveryConfig{}
+	for _, a := range actions {
+		rc.Actions = append(rc.Actions, RecoveryAction{Type: RecoveryActionType(a.Type), Delay: a.Delay})
+	}
+	period, err := s.ResetPeriod()
+	if err != nil {
+		return nil, err
+	}
+	rc.ResetPeriod = time.Duration(period) * time.Second
+	if rc.RebootMessage, err = s.RebootMessage(); err != nil {
+		return nil, err
+	}
+	if rc.Command, err = s.RecoveryCommand(); err != nil {
+		return nil, err
+	}
+	if rc.OnNonCrashFailures, err = s.RecoveryActionsOnNonCrashFailures(); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// writeRecovery sets the failure actions of s to rc, or removes them if
+// rc is nil.
+func writeRecovery(s *mgr.Service, rc *RecoveryConfig) error {
+	if rc == nil || len(rc.Actions) == 0 {
+		return s.ResetRecoveryActions()
+	}
+	var actions []mgr.RecoveryAction
+	for _, a := range rc.Actions {
+		actions = append(actions, mgr.RecoveryAction{Type: int(a.Type), Delay: a.Delay})
+	}
+	if err := s.SetRecoveryActions(actions, uint32(rc.ResetPeriod/time.Second)); err != nil {
+		return err
+	}
+	if err := s.SetRebootMessage(rc.RebootMessage); err != nil {
+		return err
+	}
+	if err := s.SetRecoveryCommand(rc.Command); err != nil {
+		return err
+	}
+	return s.SetRecoveryActionsOnNonCrashFailures(rc.OnNonCrashFailures)
+}
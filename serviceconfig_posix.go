@@ -0,0 +1,26 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+func GetServiceConfig(name string) (ServiceConfig, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) GetServiceConfig(name string) (ServiceConfig, error) {
+	panic("winsvc: only support windows!")
+}
+func DiffConfig(name string, desired ServiceConfig) ([]Change, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) DiffConfig(name string, desired ServiceConfig) ([]Change, error) {
+	panic("winsvc: only support windows!")
+}
+func DependentServices(name string) ([]string, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) DependentServices(name string) ([]string, error) {
+	panic("winsvc: only support windows!")
+}
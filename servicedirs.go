@@ -0,0 +1,14 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+// ServiceDirs are the directories a service uses, which its account must
+// be allowed to use when it is not LocalSystem, such as LocalService
+// (see PrepareServiceDirs). Empty directories are skipped, but for Data.
+type ServiceDirs struct {
+	Data   string // written by the service, the service directory in ProgramData if empty
+	Log    string // written by the service
+	Config string // read by the service
+}
@@ -0,0 +1,48 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// ServiceInfo is one entry of the service inventory.
+type ServiceInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	State       string `json:"state"`
+	StartType   string `json:"startType"`
+	BinaryPath  string `json:"binaryPath"`
+	Account     string `json:"account"`
+}
+
+// ServiceStatus is the current status of a service.
+type ServiceStatus struct {
+	Name                    string `json:"name"`
+	State                   string `json:"state"`
+	PID                     uint32 `json:"pid,omitempty"` // zero unless running
+	Win32ExitCode           uint32 `json:"win32ExitCode"`
+	ServiceSpecificExitCode uint32 `json:"serviceSpecificExitCode"`
+}
+
+// WriteInventoryJSON writes services to w as a JSON array.
+func WriteInventoryJSON(w io.Writer, services []ServiceInfo) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(services)
+}
+
+// WriteInventoryCSV writes services to w as CSV, with a header row.
+func WriteInventoryCSV(w io.Writer, services []ServiceInfo) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Name", "DisplayName", "State", "StartType", "BinaryPath", "Account"})
+	for _, s := range services {
+		cw.Write([]string{s.Name, s.DisplayName, s.State, s.StartType, s.BinaryPath, s.Account})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,171 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func GetServiceSecurity(name string) (string, error) {
+	m, err := Connect()
+	if err != nil {
+		return "", err
+	}
+	defer m.Disconnect()
+	return m.GetServiceSecurity(name)
+}
+
+func SetServiceSecurity(name, sddl string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.SetServiceSecurity(name, sddl)
+}
+
+func GrantServiceAccess(name, account string, rights ServiceRights) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.GrantServiceAccess(name, account, rights)
+}
+
+func RevokeServiceAccess(name, account string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.RevokeServiceAccess(name, account)
+}
+
+// GetServiceSecurity returns the discretionary ACL of service name in
+// SDDL, as the "D:" part of "sc sdshow".
+func (p *Manager) GetServiceSecurity(name string) (string, error) {
+	s, err := p.openService(name, windows.READ_CONTROL)
+	if err != nil {
+		return "", fmt.Errorf("winsvc.GetServiceSecurity: could not access service: %v", err)
+	}
+	defer s.Close()
+	sd, err := windows.GetSecurityInfo(s.Handle, windows.SE_SERVICE, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return "", fmt.Errorf("winsvc.GetServiceSecurity: %v", err)
+	}
+	return sd.String(), nil
+}
+
+// SetServiceSecurity replaces the discretionary ACL of service name with
+// the one of the SDDL security descriptor sddl, such as "D:(A;;CCLCSWRPWPDTLOCRRC;;;SY)...".
+// Owner, group and system ACL in sddl are ignored.
+func (p *Manager) SetServiceSecurity(name, sddl string) (err error) {
+	defer func() { p.audit("SetSecurity", name, nil, nil, err) }()
+	s, err := p.openService(name, windows.WRITE_DAC)
+	if err != nil {
+		return fmt.Errorf("winsvc.SetServiceSecurity: could not access service: %v", err)
+	}
+	defer s.Close()
+	if err := setServiceSecurity(s, sddl); err != nil {
+		return fmt.Errorf("winsvc.SetServiceSecurity: %v", err)
+	}
+	return nil
+}
+
+// setServiceSecurity sets the discretionary ACL of s to the one of sddl.
+func setServiceSecurity(s *mgr.Service, sddl string) error {
+	sd, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return fmt.Errorf("invalid security descriptor %q: %v", sddl, err)
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return fmt.Errorf("security descriptor %q has no DACL: %v", sddl, err)
+	}
+	return setServiceDACL(s, dacl)
+}
+
+// GrantServiceAccess lets account, a user or group name such as
+// `BUILTIN\Backup Operators` or a SID string, use service name with the
+// rights rights, in addition to those it already has.
+func (p *Manager) GrantServiceAccess(name, account string, rights ServiceRights) (err error) {
+	defer func() { p.audit("SetSecurity", name, nil, nil, err) }()
+	if err := p.changeServiceACL(name, account, windows.GRANT_ACCESS, rights); err != nil {
+		return fmt.Errorf("winsvc.GrantServiceAccess: %v", err)
+	}
+	return nil
+}
+
+// RevokeServiceAccess removes every right granted to account on service
+// name.
+func (p *Manager) RevokeServiceAccess(name, account string) (err error) {
+	defer func() { p.audit("SetSecurity", name, nil, nil, err) }()
+	if err := p.changeServiceACL(name, account, windows.REVOKE_ACCESS, 0); err != nil {
+		return fmt.Errorf("winsvc.RevokeServiceAccess: %v", err)
+	}
+	return nil
+}
+
+// changeServiceACL merges an entry for account into the discretionary
+// ACL of service name.
+func (p *Manager) changeServiceACL(name, account string, mode windows.ACCESS_MODE, rights ServiceRights) error {
+	sid, err := lookupAccountSID(p.host, account)
+	if err != nil {
+		return err
+	}
+	s, err := p.openService(name, windows.READ_CONTROL|windows.WRITE_DAC)
+	if err != nil {
+		return fmt.Errorf("could not access service: %v", err)
+	}
+	defer s.Close()
+	sd, err := windows.GetSecurityInfo(s.Handle, windows.SE_SERVICE, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return err
+	}
+	current, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	dacl, err := windows.ACLFromEntries([]windows.EXPLICIT_ACCESS{{
+		AccessPermissions: windows.ACCESS_MASK(rights),
+		AccessMode:        mode,
+		Inheritance:       windows.NO_INHERITANCE,
+		Trustee: windows.TRUSTEE{
+			TrusteeForm:  windows.TRUSTEE_IS_SID,
+			TrusteeType:  windows.TRUSTEE_IS_UNKNOWN,
+			TrusteeValue: windows.TrusteeValueFromSID(sid),
+		},
+	}}, current)
+	if err != nil {
+		return err
+	}
+	return setServiceDACL(s, dacl)
+}
+
+func setServiceDACL(s *mgr.Service, dacl *windows.ACL) error {
+	return windows.SetSecurityInfo(s.Handle, windows.SE_SERVICE, windows.DACL_SECURITY_INFORMATION, nil, nil, dacl, nil)
+}
+
+// lookupAccountSID returns the SID of account on host, which may be a
+// SID string.
+func lookupAccountSID(host, account string) (*windows.SID, error) {
+	if strings.HasPrefix(strings.ToUpper(account), "S-") {
+		return windows.StringToSid(account)
+	}
+	// .\user names a local account, which LookupSID does not accept
+	account = strings.TrimPrefix(account, `.\`)
+	sid, _, _, err := windows.LookupSID(host, account)
+	if err != nil {
+		return nil, fmt.Errorf("unknown account %s: %v", account, err)
+	}
+	return sid, nil
+}
@@ -0,0 +1,32 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+func GetServiceSecurity(name string) (string, error) {
+	panic("winsvc: only support windows!")
+}
+func SetServiceSecurity(name, sddl string) error {
+	panic("winsvc: only support windows!")
+}
+func GrantServiceAccess(name, account string, rights ServiceRights) error {
+	panic("winsvc: only support windows!")
+}
+func RevokeServiceAccess(name, account string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) GetServiceSecurity(name string) (string, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetServiceSecurity(name, sddl string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) GrantServiceAccess(name, account string, rights ServiceRights) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) RevokeServiceAccess(name, account string) error {
+	panic("winsvc: only support windows!")
+}
@@ -0,0 +1,131 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+// ActiveSessionID returns the session attached to the physical console.
+func ActiveSessionID() (uint32, error) {
+	id := windows.WTSGetActiveConsoleSessionId()
+	if id == 0xFFFFFFFF {
+		return 0, errors.New("winsvc.ActiveSessionID: no session is attached to the console")
+	}
+	return id, nil
+}
+
+// StartInSession starts appPath with args as the user logged on in
+// session sessionID, on the interactive desktop of that session. The
+// caller must run as LocalSystem, as services do.
+func StartInSession(sessionID uint32, appPath string, args ...string) (*os.Process, error) {
+	var token windows.Token
+	if err := windows.WTSQueryUserToken(sessionID, &token); err != nil {
+		return nil, fmt.Errorf("winsvc.StartInSession: could not get the user of session %d: %v", sessionID, err)
+	}
+	defer token.Close()
+	var env *uint16
+	if err := windows.CreateEnvironmentBlock(&env, token, false); err != nil {
+		return nil, fmt.Errorf("winsvc.StartInSession: %v", err)
+	}
+	defer windows.DestroyEnvironmentBlock(env)
+
+	cmdLine, err := windows.UTF16PtrFromString(windows.ComposeCommandLine(append([]string{appPath}, args...)))
+	if err != nil {
+		return nil, err
+	}
+	desktop, _ := windows.UTF16PtrFromString(`winsta0\default`)
+	si := windows.StartupInfo{Desktop: desktop}
+	si.Cb = uint32(unsafe.Sizeof(si))
+	var pi windows.ProcessInformation
+	if err := windows.CreateProcessAsUser(token, nil, cmdLine, nil, nil, false,
+		windows.CREATE_UNICODE_ENVIRONMENT, env, nil, &si, &pi); err != nil {
+		return nil, fmt.Errorf("winsvc.StartInSession: could not start %s: %v", appPath, err)
+	}
+	defer windows.CloseHandle(pi.Thread)
+	defer windows.CloseHandle(pi.Process)
+	return os.FindProcess(int(pi.ProcessId))
+}
+
+// sessionHelper keeps a helper process running in the user sessions.
+type sessionHelper struct {
+	p       *serviceRuntime
+	appPath string
+	args    []string
+
+	mu    sync.Mutex
+	procs map[uint32]*os.Process
+}
+
+func (p *serviceRuntime) newSessionHelper() *sessionHelper {
+	o := p.opts.sessionHelper
+	return &sessionHelper{p: p, appPath: o.appPath, args: o.args, procs: make(map[uint32]*os.Process)}
+}
+
+// startAll starts the helper in every session with a logged on user.
+func (h *sessionHelper) startAll() {
+	var sessions *windows.WTS_SESSION_INFO
+	var count uint32
+	if err := windows.WTSEnumerateSessions(0, 0, 1, &sessions, &count); err != nil {
+		h.p.elog.Error(1, fmt.Sprintf("winsvc.Execute: could not list sessions: %v", err))
+		return
+	}
+	defer windows.WTSFreeMemory(uintptr(unsafe.Pointer(sessions)))
+	for _, s := range unsafe.Slice(sessions, count) {
+		if s.State == windows.WTSActive {
+			h.start(s.SessionID)
+		}
+	}
+}
+
+// start starts the helper in session id, unless it runs there already.
+func (h *sessionHelper) start(id uint32) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.procs[id]; ok {
+		return
+	}
+	proc, err := StartInSession(id, h.appPath, h.args...)
+	if err != nil {
+		h.p.elog.Error(1, fmt.Sprintf("winsvc.Execute: %v", err))
+		return
+	}
+	h.procs[id] = proc
+	go func() {
+		proc.Wait()
+		h.mu.Lock()
+		if h.procs[id] == proc {
+			delete(h.procs, id)
+		}
+		h.mu.Unlock()
+	}()
+}
+
+// sessionChange starts the helper when a user logs on. The session is
+// found by listing the sessions, EventData is not valid by now.
+func (h *sessionHelper) sessionChange(c svc.ChangeRequest) {
+	if c.EventType == windows.WTS_SESSION_LOGON {
+		h.startAll()
+	}
+}
+
+// stop kills the helpers.
+func (h *sessionHelper) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, proc := range h.procs {
+		proc.Kill()
+		delete(h.procs, id)
+	}
+}
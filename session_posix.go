@@ -0,0 +1,18 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+import (
+	"os"
+)
+
+func ActiveSessionID() (uint32, error) {
+	panic("winsvc: only support windows!")
+}
+func StartInSession(sessionID uint32, appPath string, args ...string) (*os.Process, error) {
+	panic("winsvc: only support windows!")
+}
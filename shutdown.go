@@ -0,0 +1,97 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Shutdowner is a server which stops gracefully, such as *http.Server.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+type (
+	stopTimeoutKey struct{}
+	failKey        struct{}
+)
+
+// fail stops the service started with ctx because of err. It returns
+// err for a context not given by winsvc, which has no service to stop.
+func fail(ctx context.Context, err error) error {
+	if fn, ok := ctx.Value(failKey{}).(func(error)); ok {
+		fn(err)
+		return nil
+	}
+	return err
+}
+
+// drainTimeout returns how long a server started with ctx may take to
+// shut down: the stop timeout of the service (see WithStopTimeout) less
+// a second to report the stop, or Defaults().Timeout without one.
+func drainTimeout(ctx context.Context) time.Duration {
+	d, _ := ctx.Value(stopTimeoutKey{}).(time.Duration)
+	switch {
+	case d <= 0:
+		return Defaults().Timeout
+	case d > 2*time.Second:
+		return d - time.Second
+	}
+	return d
+}
+
+// ServeUntilStopped returns a start function for RunAsServiceContext
+// which calls serve, and shuts s down gracefully when the service stops,
+// giving it the stop timeout of the service to drain. If serve fails
+// otherwise than with http.ErrServerClosed or net.ErrClosed, the service
+// stops with the error. Outside a service, such as in a console run,
+// the error is dropped: use ServeContext to get it.
+func ServeUntilStopped(s Shutdowner, serve func() error) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		if err := ServeContext(ctx, s, serve); err != nil {
+			fail(ctx, err)
+		}
+	}
+}
+
+// ServeContext calls serve, and shuts s down gracefully once ctx is
+// done, as the start function of ServeUntilStopped does. It returns the
+// error serve fails with, other than http.ErrServerClosed and
+// net.ErrClosed.
+func ServeContext(ctx context.Context, s Shutdowner, serve func() error) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- serve()
+	}()
+	select {
+	case err := <-errc:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+	sctx, cancel := context.WithTimeout(context.Background(), drainTimeout(ctx))
+	defer cancel()
+	s.Shutdown(sctx)
+	<-errc
+	return nil
+}
+
+// HTTPServer returns a start function for RunAsServiceContext serving srv
+// on l, or on srv.Addr if l is nil (see also WithListener), and shutting
+// srv down gracefully when the service stops.
+func HTTPServer(srv *http.Server, l net.Listener) func(ctx context.Context) {
+	return ServeUntilStopped(srv, func() error {
+		if l != nil {
+			return srv.Serve(l)
+		}
+		return srv.ListenAndServe()
+	})
+}
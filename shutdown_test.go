@@ -0,0 +1,51 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// A server which cannot listen, run outside a service, returns the error
+// instead of panicking.
+func TestServeOutsideService(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	srv := &http.Server{Addr: l.Addr().String()}
+	if err := ServeContext(context.Background(), srv, srv.ListenAndServe); err == nil {
+		t.Error("ServeContext on a taken port returned nil")
+	}
+	HTTPServer(&http.Server{Addr: l.Addr().String()}, nil)(context.Background())
+}
+
+// A server is shut down once its context is done.
+func TestServeContextShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ServeContext(ctx, srv, func() error { return srv.Serve(l) })
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeContext returned %v after shutdown, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("ServeContext did not return after its context was done")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LogEntry is an event log entry about a service.
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Log     string    `json:"log"`
+	Source  string    `json:"source"`
+	EventID uint32    `json:"eventId"` // without the severity bits
+	Level   string    `json:"level"`
+	Strings []string  `json:"strings,omitempty"` // the insertion strings of the message
+}
+
+func (e LogEntry) String() string {
+	return fmt.Sprintf("%s %s/%s %d %s: %s", e.Time.Format("2006-01-02 15:04:05"), e.Log, e.Source, e.EventID, e.Level, strings.Join(e.Strings, "; "))
+}
+
+// StartFailure is the outcome of ExplainStartFailure: the status of the
+// service, its run history and the event log entries about its last
+// start, with the likely causes of the failure drawn from them.
+type StartFailure struct {
+	Service                 string     `json:"service"`
+	State                   string     `json:"state"`
+	Win32ExitCode           uint32     `json:"win32ExitCode"`
+	ServiceSpecificExitCode uint32     `json:"serviceSpecificExitCode"`
+	ProcessStarted          bool       `json:"processStarted"` // the runtime of this package ran since the failure window began
+	LastStart               time.Time  `json:"lastStart,omitempty"`
+	Entries                 []LogEntry `json:"entries"` // newest first
+	Causes                  []string   `json:"causes"`
+}
+
+func (f *StartFailure) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s", f.Service, f.State)
+	if f.Win32ExitCode != 0 {
+		fmt.Fprintf(&b, ", exit code %d", f.Win32ExitCode)
+	}
+	if f.Win32ExitCode == errorServiceSpecificError {
+		fmt.Fprintf(&b, ", service specific exit code %d", f.ServiceSpecificExitCode)
+	}
+	b.WriteString("):")
+	if len(f.Causes) == 0 {
+		b.WriteString(" no cause found")
+	}
+	for _, c := range f.Causes {
+		fmt.Fprintf(&b, "\n  %s", c)
+	}
+	for _, e := range f.Entries {
+		fmt.Fprintf(&b, "\n  > %v", e)
+	}
+	return b.String()
+}
+
+func (f *StartFailure) cause(format string, args ...interface{}) {
+	f.Causes = append(f.Causes, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,41 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+// IsRunning reports whether service name is Running. It fails if the
+// service is not installed or cannot be queried.
+func IsRunning(name string) (bool, error) {
+	return isState(QueryService, name, "Running")
+}
+
+// IsStopped reports whether service name is Stopped.
+func IsStopped(name string) (bool, error) {
+	return isState(QueryService, name, "Stopped")
+}
+
+// IsPaused reports whether service name is Paused.
+func IsPaused(name string) (bool, error) {
+	return isState(QueryService, name, "Paused")
+}
+
+func (p *Manager) IsRunning(name string) (bool, error) {
+	return isState(p.Query, name, "Running")
+}
+
+func (p *Manager) IsStopped(name string) (bool, error) {
+	return isState(p.Query, name, "Stopped")
+}
+
+func (p *Manager) IsPaused(name string) (bool, error) {
+	return isState(p.Query, name, "Paused")
+}
+
+func isState(query func(name string) (string, error), name, state string) (bool, error) {
+	s, err := query(name)
+	if err != nil {
+		return false, err
+	}
+	return s == state, nil
+}
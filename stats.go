@@ -0,0 +1,68 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"time"
+)
+
+// ServiceStats is the run history of a service, recorded by the runtime
+// of this package each time the service starts and stops.
+type ServiceStats struct {
+	Starts       uint32    // times the service started
+	CleanStops   uint32    // stops with a zero exit code
+	UncleanStops uint32    // stops with an exit code, and crashes
+	LastStart    time.Time // zero if the service never started
+	LastStop     time.Time // zero if the service never stopped
+	Running      bool      // started and not stopped since
+
+	// LastWin32ExitCode and LastServiceSpecificExitCode are the exit
+	// codes of the last stop, as in ServiceStatus.
+	LastWin32ExitCode           uint32
+	LastServiceSpecificExitCode uint32
+
+	// RecentFailures are the last unclean stops since the last clean
+	// one, oldest first, at most maxRecentFailures of them.
+	RecentFailures []StopRecord
+}
+
+// maxRecentFailures is how many unclean stops ServiceStats keeps.
+const maxRecentFailures = 10
+
+// StopRecord is an unclean stop of a service. Reason is as given to
+// Hook.Stopped, or "Crashed", with no exit code, if the service never
+// recorded its stop. The exit codes are as in ServiceStatus.
+type StopRecord struct {
+	Time                    time.Time
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	Reason                  string
+}
+
+// stopExitCodes returns the exit codes of ServiceStatus for the exit
+// code errno returned by Execute, service specific if ssec is set.
+func stopExitCodes(ssec bool, errno uint32) (win32, specific uint32) {
+	if ssec && errno != 0 {
+		return errorServiceSpecificError, errno
+	}
+	return errno, 0
+}
+
+// addFailure records an unclean stop in s.
+func (s *ServiceStats) addFailure(f StopRecord) {
+	s.RecentFailures = append(s.RecentFailures, f)
+	if n := len(s.RecentFailures); n > maxRecentFailures {
+		s.RecentFailures = s.RecentFailures[n-maxRecentFailures:]
+	}
+}
+
+// Uptime returns how long the service has been running, or zero if it
+// is not running.
+func (s ServiceStats) Uptime() time.Duration {
+	if !s.Running || s.LastStart.IsZero() {
+		return 0
+	}
+	return time.Since(s.LastStart)
+}
@@ -0,0 +1,233 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+func statsKeyPath(name string) string {
+	return parametersKeyPath(name) + `\Stats`
+}
+
+// Stats returns the run history of service name, stored under the
+// Parameters\Stats registry key of the service.
+func Stats(name string) (ServiceStats, error) {
+	return readStats("", name)
+}
+
+func (p *Manager) Stats(name string) (ServiceStats, error) {
+	return readStats(p.host, name)
+}
+
+func readStats(host, name string) (ServiceStats, error) {
+	hklm, err := openLocalMachine(host)
+	if err != nil {
+		return ServiceStats{}, err
+	}
+	defer closeLocalMachine(hklm)
+	k, err := registry.OpenKey(hklm, statsKeyPath(name), registry.QUERY_VALUE|keyView)
+	if err != nil {
+		if err == registry.ErrNotExist {
+			return ServiceStats{}, nil
+		}
+		return ServiceStats{}, fmt.Errorf("winsvc.Stats: could not open Stats of %s: %v", name, err)
+	}
+	defer k.Close()
+	dword := func(name string) uint32 {
+		v, _, _ := k.GetIntegerValue(name)
+		return uint32(v)
+	}
+	stamp := func(name string) time.Time {
+		if v, _, err := k.GetIntegerValue(name); err == nil && v != 0 {
+			return time.Unix(int64(v), 0)
+		}
+		return time.Time{}
+	}
+	var failures []StopRecord
+	records, _, _ := k.GetStringsValue("RecentFailures")
+	for _, r := range records {
+		// "time win32 specific reason"
+		fields := strings.SplitN(r, " ", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		unix, err1 := strconv.ParseInt(fields[0], 10, 64)
+		win32, err2 := strconv.ParseUint(fields[1], 10, 32)
+		specific, err3 := strconv.ParseUint(fields[2], 10, 32)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		failures = append(failures, StopRecord{
+			Time:                    time.Unix(unix, 0),
+			Win32ExitCode:           uint32(win32),
+			ServiceSpecificExitCode: uint32(specific),
+			Reason:                  fields[3],
+		})
+	}
+	return ServiceStats{
+		Starts:       dword("Starts"),
+		CleanStops:   dword("CleanStops"),
+		UncleanStops: dword("UncleanStops"),
+		LastStart:    stamp("LastStart"),
+		LastStop:     stamp("LastStop"),
+		Running:      dword("Running") != 0,
+
+		LastWin32ExitCode:           dword("LastWin32ExitCode"),
+		LastServiceSpecificExitCode: dword("LastServiceSpecificExitCode"),
+
+		RecentFailures: failures,
+	}, nil
+}
+
+// grantStatsAccess creates the Stats key of service name configured as
+// cfg, and lets the accounts the service runs as write it: those other
+// than LocalSystem, such as LocalService, cannot write the key of the
+// service otherwise.
+func (p *Manager) grantStatsAccess(name string, cfg ServiceConfig) error {
+	accounts := accessAccounts(name, cfg)
+	if len(accounts) == 0 {
+		return nil
+	}
+	hklm, err := openLocalMachine(p.host)
+	if err != nil {
+		return err
+	}
+	defer closeLocalMachine(hklm)
+	k, _, err := registry.CreateKey(hklm, statsKeyPath(name), windows.READ_CONTROL|windows.WRITE_DAC|keyView)
+	if err != nil {
+		return fmt.Errorf("could not create Stats of %s: %v", name, err)
+	}
+	defer k.Close()
+	sd, err := windows.GetSecurityInfo(windows.Handle(k), windows.SE_REGISTRY_KEY, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		sid, err := lookupAccountSID(p.host, account)
+		if err != nil {
+			return err
+		}
+		if dacl, err = grantEntry(dacl, sid, windows.KEY_READ|windows.KEY_WRITE); err != nil {
+			return err
+		}
+	}
+	return windows.SetSecurityInfo(windows.Handle(k), windows.SE_REGISTRY_KEY, windows.DACL_SECURITY_INFORMATION, nil, nil, dacl, nil)
+}
+
+// saveStats writes s as the stats of service name.
+func (p *serviceRuntime) saveStats(name string, s ServiceStats) error {
+	if p.writeStats != nil {
+		return p.writeStats(name, s)
+	}
+	return writeStats(name, s)
+}
+
+func writeStats(name string, s ServiceStats) error {
+	k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, statsKeyPath(name), registry.SET_VALUE|keyView)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	stamp := func(t time.Time) uint64 {
+		if t.IsZero() {
+			return 0
+		}
+		return uint64(t.Unix())
+	}
+	running := uint32(0)
+	if s.Running {
+		running = 1
+	}
+	for _, v := range []struct {
+		name  string
+		value uint32
+	}{
+		{"Starts", s.Starts},
+		{"CleanStops", s.CleanStops},
+		{"UncleanStops", s.UncleanStops},
+		{"Running", running},
+		{"LastWin32ExitCode", s.LastWin32ExitCode},
+		{"LastServiceSpecificExitCode", s.LastServiceSpecificExitCode},
+	} {
+		if err := k.SetDWordValue(v.name, v.value); err != nil {
+			return err
+		}
+	}
+	if err := k.SetQWordValue("LastStart", stamp(s.LastStart)); err != nil {
+		return err
+	}
+	records := []string{}
+	for _, f := range s.RecentFailures {
+		records = append(records, fmt.Sprintf("%d %d %d %s", f.Time.Unix(), f.Win32ExitCode, f.ServiceSpecificExitCode, f.Reason))
+	}
+	if err := k.SetStringsValue("RecentFailures", records); err != nil {
+		return err
+	}
+	return k.SetQWordValue("LastStop", stamp(s.LastStop))
+}
+
+// recordStart records that the service starts, and returns its stats.
+// A previous run which never recorded its stop crashed.
+func (p *serviceRuntime) recordStart() ServiceStats {
+	name := p.serviceName()
+	s, err := readStats("", name)
+	if err == nil {
+		if s.Running {
+			s.UncleanStops++
+			s.addFailure(StopRecord{Time: time.Now(), Reason: "Crashed"})
+		}
+		s.Starts++
+		s.LastStart = time.Now()
+		s.Running = true
+		err = p.saveStats(name, s)
+	}
+	if err != nil {
+		p.elog.Warning(1, fmt.Sprintf("winsvc.Execute: could not record service start, the crash loop breaker is off: %v", err))
+	}
+	return s
+}
+
+// recordStop records that the service stops with the exit code errno
+// returned by Execute, service specific if ssec is set, because of
+// reason (see Hook.Stopped). A refusal to start because of a crash loop
+// is not recorded as a failure, so that the breaker resets once the
+// failures are older than its window.
+func (p *serviceRuntime) recordStop(reason string, ssec bool, errno uint32) {
+	name := p.serviceName()
+	win32, specific := stopExitCodes(ssec, errno)
+	s, err := readStats("", name)
+	if err == nil {
+		switch {
+		case errno == 0:
+			s.CleanStops++
+			s.RecentFailures = nil
+		case reason == "CrashLoop":
+			s.UncleanStops++
+		default:
+			s.UncleanStops++
+			s.addFailure(StopRecord{Time: time.Now(), Win32ExitCode: win32, ServiceSpecificExitCode: specific, Reason: reason})
+		}
+		s.LastStop = time.Now()
+		s.LastWin32ExitCode, s.LastServiceSpecificExitCode = win32, specific
+		s.Running = false
+		err = p.saveStats(name, s)
+	}
+	if err != nil {
+		p.elog.Warning(1, fmt.Sprintf("winsvc.Execute: could not record service stop: %v", err))
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"time"
+)
+
+// StatusReporter is given to the start function of RunAsServiceWithStatus,
+// so it can report its progress while the service is starting.
+type StatusReporter interface {
+	// Pending reports that the service is still starting and the next
+	// report is due within waitHint. Each call advances the checkpoint.
+	Pending(waitHint time.Duration)
+
+	// Running reports that the service has started. Later calls to
+	// Pending or Running have no effect.
+	Running()
+}
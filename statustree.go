@@ -0,0 +1,117 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// statusNode is a service of the status --tree command, with the
+// services it depends on. Groups of services ("+Group") are shown with
+// the "Group" state, and services which cannot be queried with Error.
+type statusNode struct {
+	ServiceStatus
+	Error        string          `json:"error,omitempty"`
+	Dependencies []statusNode    `json:"dependencies,omitempty"`
+	Dependents   []ServiceStatus `json:"dependents,omitempty"` // of the root only
+}
+
+// statusTree returns the status of service name, of the services it
+// depends on, recursively, and of the services depending on it.
+func statusTree(name string) (*statusNode, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	root := m.statusNode(name, map[string]bool{})
+	if root.Error != "" {
+		return nil, errors.New(root.Error)
+	}
+	dependents, err := m.DependentServices(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dependents {
+		status, err := m.QueryStatus(d)
+		if err != nil {
+			status = ServiceStatus{Name: d, State: "Unknown"}
+		}
+		root.Dependents = append(root.Dependents, status)
+	}
+	return &root, nil
+}
+
+// statusNode queries service name and its dependencies. seen holds the
+// services on the path from the root, to stop at dependency cycles.
+func (p *Manager) statusNode(name string, seen map[string]bool) statusNode {
+	if strings.HasPrefix(name, "+") {
+		return statusNode{ServiceStatus: ServiceStatus{Name: name, State: "Group"}}
+	}
+	n := statusNode{ServiceStatus: ServiceStatus{Name: name, State: "Unknown"}}
+	status, err := p.QueryStatus(name)
+	if err != nil {
+		n.Error = err.Error()
+		return n
+	}
+	n.ServiceStatus = status
+	key := strings.ToLower(name)
+	if seen[key] {
+		return n
+	}
+	seen[key] = true
+	defer delete(seen, key)
+	cfg, err := p.GetServiceConfig(name)
+	if err != nil {
+		n.Error = err.Error()
+		return n
+	}
+	for _, d := range cfg.Dependencies {
+		n.Dependencies = append(n.Dependencies, p.statusNode(d, seen))
+	}
+	return n
+}
+
+// writeStatusTree writes root to w as an indented tree, followed by the
+// services depending on it.
+func writeStatusTree(w io.Writer, root *statusNode) {
+	var write func(n statusNode, indent string)
+	write = func(n statusNode, indent string) {
+		fmt.Fprintf(w, "%s%s\n", indent, formatNodeStatus(n.ServiceStatus, n.Error))
+		for _, d := range n.Dependencies {
+			write(d, indent+"  ")
+		}
+	}
+	fmt.Fprintln(w, formatNodeStatus(root.ServiceStatus, root.Error))
+	if len(root.Dependencies) > 0 {
+		fmt.Fprintln(w, "  depends on:")
+		for _, d := range root.Dependencies {
+			write(d, "    ")
+		}
+	}
+	if len(root.Dependents) > 0 {
+		fmt.Fprintln(w, "  needed by:")
+		for _, d := range root.Dependents {
+			fmt.Fprintf(w, "    %s\n", formatNodeStatus(d, ""))
+		}
+	}
+}
+
+func formatNodeStatus(s ServiceStatus, err string) string {
+	line := fmt.Sprintf("%s: %s", s.Name, s.State)
+	if s.PID != 0 {
+		line += fmt.Sprintf(" (pid %d)", s.PID)
+	}
+	if s.State == "Stopped" && (s.Win32ExitCode != 0 || s.ServiceSpecificExitCode != 0) {
+		line += fmt.Sprintf(" (exit code %d/%d)", s.Win32ExitCode, s.ServiceSpecificExitCode)
+	}
+	if err != "" {
+		line += " (" + err + ")"
+	}
+	return line
+}
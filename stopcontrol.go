@@ -0,0 +1,137 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+)
+
+var procControlServiceExW = modadvapi32.NewProc("ControlServiceExW")
+
+const serviceControlStatusReasonInfo = 1 // SERVICE_CONTROL_STATUS_REASON_INFO
+
+// serviceControlStatusReasonParams is SERVICE_CONTROL_STATUS_REASON_PARAMSW.
+type serviceControlStatusReasonParams struct {
+	reason  uint32
+	comment *uint16
+	status  windows.SERVICE_STATUS_PROCESS
+}
+
+// stopReasonMaxAge is how long a stop reason left for a service is
+// taken as the reason of its next stop.
+const stopReasonMaxAge = time.Minute
+
+func stopReasonKeyPath(name string) string {
+	return parametersKeyPath(name) + `\StopReason`
+}
+
+func StopServiceWithReason(name string, r StopReason, timeout time.Duration) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.StopWithReason(name, r, timeout)
+}
+
+// StopWithReason stops service name like StopAndWait, waiting up to
+// timeout for it to stop (zero waits for Defaults().Timeout), and gives
+// the service control manager the reason r, which it records in the
+// System event log. The service receives r too when it runs with
+// WithStopReason: Windows does not pass the reason on, so it is left
+// for the service under the Parameters\StopReason registry key.
+func (p *Manager) StopWithReason(name string, r StopReason, timeout time.Duration) (err error) {
+	if len([]rune(r.Comment)) > maxStopComment {
+		r.Comment = string([]rune(r.Comment)[:maxStopComment])
+	}
+	defer func() {
+		p.auditEvent(AuditEvent{Operation: "Stop", Service: name, StopReason: &r, Err: err})
+	}()
+	s, err := p.openService(name, windows.SERVICE_STOP|windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return fmt.Errorf("winsvc.StopService: could not access service: %v", err)
+	}
+	defer s.Close()
+	if err := writeStopReason(p.host, name, r); err != nil {
+		return fmt.Errorf("winsvc.StopService: could not record stop reason: %v", err)
+	}
+	params := serviceControlStatusReasonParams{reason: r.Code()}
+	if r.Comment != "" {
+		if params.comment, err = windows.UTF16PtrFromString(r.Comment); err != nil {
+			return fmt.Errorf("winsvc.StopService: %v", err)
+		}
+	}
+	err = p.retryAction(windows.ERROR_SERVICE_NOT_ACTIVE, func() error {
+		ok, _, e := procControlServiceExW.Call(uintptr(s.Handle), uintptr(svc.Stop), serviceControlStatusReasonInfo, uintptr(unsafe.Pointer(&params)))
+		if ok == 0 {
+			return e
+		}
+		return nil
+	})
+	if err != nil {
+		removeStopReason(p.host, name)
+		return fmt.Errorf("winsvc.StopService: could not send control=%d: %v", svc.Stop, err)
+	}
+	if svc.State(params.status.CurrentState) == svc.Stopped {
+		return nil
+	}
+	_, err = waitState(s, svc.Stopped, timeout)
+	return err
+}
+
+func writeStopReason(host, name string, r StopReason) error {
+	hklm, err := openLocalMachine(host)
+	if err != nil {
+		return err
+	}
+	defer closeLocalMachine(hklm)
+	k, _, err := registry.CreateKey(hklm, stopReasonKeyPath(name), registry.SET_VALUE|keyView)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if err := k.SetDWordValue("Code", r.Code()); err != nil {
+		return err
+	}
+	if err := k.SetStringValue("Comment", r.Comment); err != nil {
+		return err
+	}
+	return k.SetQWordValue("Time", uint64(time.Now().Unix()))
+}
+
+func removeStopReason(host, name string) {
+	hklm, err := openLocalMachine(host)
+	if err != nil {
+		return
+	}
+	defer closeLocalMachine(hklm)
+	registry.DeleteKey(hklm, stopReasonKeyPath(name))
+}
+
+// takeStopReason returns and removes the stop reason left for the
+// running service name by StopWithReason, if a recent one is there.
+func takeStopReason(name string) (StopReason, bool) {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, stopReasonKeyPath(name), registry.QUERY_VALUE|keyView)
+	if err != nil {
+		return StopReason{}, false
+	}
+	code, _, err1 := k.GetIntegerValue("Code")
+	comment, _, _ := k.GetStringValue("Comment")
+	stamp, _, err2 := k.GetIntegerValue("Time")
+	k.Close()
+	removeStopReason("", name)
+	if err1 != nil || err2 != nil || time.Since(time.Unix(int64(stamp), 0)) > stopReasonMaxAge {
+		return StopReason{}, false
+	}
+	return stopReasonFromCode(uint32(code), comment), true
+}
@@ -0,0 +1,18 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+import (
+	"time"
+)
+
+func StopServiceWithReason(name string, r StopReason, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) StopWithReason(name string, r StopReason, timeout time.Duration) error {
+	panic("winsvc: only support windows!")
+}
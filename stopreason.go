@@ -0,0 +1,109 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+)
+
+// StopMajor and StopMinor are the major and minor reason codes of a
+// service stop. The values match the SERVICE_STOP_REASON_MAJOR_* and
+// SERVICE_STOP_REASON_MINOR_* constants of Windows.
+type (
+	StopMajor uint32
+	StopMinor uint32
+)
+
+const (
+	StopMajorOther           StopMajor = 0x00010000
+	StopMajorHardware        StopMajor = 0x00020000
+	StopMajorOperatingSystem StopMajor = 0x00030000
+	StopMajorSoftware        StopMajor = 0x00040000
+	StopMajorApplication     StopMajor = 0x00050000
+	StopMajorNone            StopMajor = 0x00060000
+)
+
+const (
+	StopMinorOther                   StopMinor = 0x00000001
+	StopMinorMaintenance             StopMinor = 0x00000002
+	StopMinorInstallation            StopMinor = 0x00000003
+	StopMinorUpgrade                 StopMinor = 0x00000004
+	StopMinorReconfig                StopMinor = 0x00000005
+	StopMinorHung                    StopMinor = 0x00000006
+	StopMinorUnstable                StopMinor = 0x00000007
+	StopMinorDisk                    StopMinor = 0x00000008
+	StopMinorNetworkCard             StopMinor = 0x00000009
+	StopMinorEnvironment             StopMinor = 0x0000000a
+	StopMinorHardwareDriver          StopMinor = 0x0000000b
+	StopMinorOtherDriver             StopMinor = 0x0000000c
+	StopMinorServicePack             StopMinor = 0x0000000d
+	StopMinorSoftwareUpdate          StopMinor = 0x0000000e
+	StopMinorSecurityFix             StopMinor = 0x0000000f
+	StopMinorSecurity                StopMinor = 0x00000010
+	StopMinorNetworkConnectivity     StopMinor = 0x00000011
+	StopMinorWMI                     StopMinor = 0x00000012
+	StopMinorServicePackUninstall    StopMinor = 0x00000013
+	StopMinorSoftwareUpdateUninstall StopMinor = 0x00000014
+	StopMinorSecurityFixUninstall    StopMinor = 0x00000015
+	StopMinorMMC                     StopMinor = 0x00000016
+	StopMinorNone                    StopMinor = 0x00000017
+)
+
+const (
+	stopFlagUnplanned = 0x10000000
+	stopFlagCustom    = 0x20000000
+	stopFlagPlanned   = 0x40000000
+)
+
+// maxStopComment is the longest comment the service control manager
+// accepts with a stop reason, in characters.
+const maxStopComment = 127
+
+// StopReason is why a service is stopped (see StopServiceWithReason and
+// WithStopReason). The service control manager records it in the System
+// event log.
+type StopReason struct {
+	Planned bool // a planned stop, such as for maintenance, not a failure
+	Major   StopMajor
+	Minor   StopMinor
+	Comment string // at most 127 characters
+}
+
+// Code returns the SERVICE_STOP_REASON value of r. Major codes from
+// 0x00400000 and minor codes from 0x0100 are custom codes.
+func (r StopReason) Code() uint32 {
+	code := uint32(r.Major)&0x00ff0000 | uint32(r.Minor)&0x0000ffff
+	if r.Planned {
+		code |= stopFlagPlanned
+	} else {
+		code |= stopFlagUnplanned
+	}
+	if r.Major >= 0x00400000 || r.Minor >= 0x0100 {
+		code |= stopFlagCustom
+	}
+	return code
+}
+
+func (r StopReason) String() string {
+	kind := "unplanned"
+	if r.Planned {
+		kind = "planned"
+	}
+	s := fmt.Sprintf("%s stop (major %d, minor %d)", kind, uint32(r.Major)>>16, uint32(r.Minor))
+	if r.Comment != "" {
+		s += ": " + r.Comment
+	}
+	return s
+}
+
+// stopReasonFromCode returns the reason encoded as code by Code.
+func stopReasonFromCode(code uint32, comment string) StopReason {
+	return StopReason{
+		Planned: code&stopFlagPlanned != 0,
+		Major:   StopMajor(code & 0x00ff0000),
+		Minor:   StopMinor(code & 0x0000ffff),
+		Comment: comment,
+	}
+}
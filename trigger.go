@@ -0,0 +1,117 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Layouts of SERVICE_TRIGGER_INFO and friends, which x/sys does not define.
+type serviceTriggerInfo struct {
+	count    uint32
+	triggers *serviceTrigger
+	reserved *byte
+}
+
+type serviceTrigger struct {
+	triggerType uint32
+	action      uint32
+	subtype     *windows.GUID
+	dataCount   uint32
+	data        *serviceTriggerDataItem
+}
+
+type serviceTriggerDataItem struct {
+	dataType uint32
+	size     uint32
+	data     *byte
+}
+
+// queryServiceConfig2 returns the QueryServiceConfig2 information at level.
+func queryServiceConfig2(h windows.Handle, level uint32) ([]byte, error) {
+	n := uint32(1024)
+	for {
+		b := make([]byte, n)
+		err := windows.QueryServiceConfig2(h, level, &b[0], n, &n)
+		if err == nil {
+			return b, nil
+		}
+		if err != syscall.ERROR_INSUFFICIENT_BUFFER || n <= uint32(len(b)) {
+			return nil, err
+		}
+	}
+}
+
+func queryTriggers(h windows.Handle) ([]Trigger, error) {
+	b, err := queryServiceConfig2(h, windows.SERVICE_CONFIG_TRIGGER_INFO)
+	if err != nil {
+		return nil, err
+	}
+	info := (*serviceTriggerInfo)(unsafe.Pointer(&b[0]))
+	if info.count == 0 || info.triggers == nil {
+		return nil, nil
+	}
+	var triggers []Trigger
+	for _, t := range unsafe.Slice(info.triggers, info.count) {
+		trigger := Trigger{
+			Type:   TriggerType(t.triggerType),
+			Action: TriggerAction(t.action),
+		}
+		if t.subtype != nil {
+			trigger.Subtype = t.subtype.String()
+		}
+		if t.data != nil {
+			for _, d := range unsafe.Slice(t.data, t.dataCount) {
+				item := TriggerData{Type: TriggerDataType(d.dataType)}
+				if d.data != nil && d.size > 0 {
+					item.Data = append([]byte(nil), unsafe.Slice(d.data, d.size)...)
+				}
+				trigger.Data = append(trigger.Data, item)
+			}
+		}
+		triggers = append(triggers, trigger)
+	}
+	return triggers, nil
+}
+
+// setTriggers replaces the triggers of the service, removing them all
+// when triggers is empty.
+func setTriggers(h windows.Handle, triggers []Trigger) error {
+	var info serviceTriggerInfo
+	if len(triggers) > 0 {
+		raw := make([]serviceTrigger, len(triggers))
+		for i, t := range triggers {
+			raw[i].triggerType = uint32(t.Type)
+			raw[i].action = uint32(t.Action)
+			if t.Subtype != "" {
+				guid, err := windows.GUIDFromString(t.Subtype)
+				if err != nil {
+					return err
+				}
+				raw[i].subtype = &guid
+			}
+			if len(t.Data) > 0 {
+				items := make([]serviceTriggerDataItem, len(t.Data))
+				for j, d := range t.Data {
+					items[j].dataType = uint32(d.Type)
+					items[j].size = uint32(len(d.Data))
+					if len(d.Data) > 0 {
+						items[j].data = &d.Data[0]
+					}
+				}
+				raw[i].dataCount = uint32(len(items))
+				raw[i].data = &items[0]
+			}
+		}
+		info.count = uint32(len(raw))
+		info.triggers = &raw[0]
+	}
+	return windows.ChangeServiceConfig2(h, windows.SERVICE_CONFIG_TRIGGER_INFO, (*byte)(unsafe.Pointer(&info)))
+}
@@ -0,0 +1,124 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procEventRegister   = modadvapi32.NewProc("EventRegister")
+	procEventWrite      = modadvapi32.NewProc("EventWrite")
+	procEventUnregister = modadvapi32.NewProc("EventUnregister")
+)
+
+// eventDescriptor is EVENT_DESCRIPTOR.
+type eventDescriptor struct {
+	id      uint16
+	version uint8
+	channel uint8
+	level   uint8
+	opcode  uint8
+	task    uint16
+	keyword uint64
+}
+
+func TriggerStart(name string, params map[string]string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.TriggerStart(name, params)
+}
+
+// TriggerStart wakes service name the way its triggers do: it stores
+// params, if any, under the Parameters registry key of the service (see
+// SetParameters) for the service to read as it starts, then fires the
+// first custom start trigger of the service (see TriggerCustom), with
+// the level and keywords the trigger asks for. Without such a trigger,
+// or on a remote computer, it starts the service instead. It does not
+// wait for the service to start; a service already running is left as
+// is, with the new params.
+func (p *Manager) TriggerStart(name string, params map[string]string) (err error) {
+	defer func() { p.audit("Start", name, nil, nil, err) }()
+	if len(params) > 0 {
+		if err := p.SetParameters(name, params); err != nil {
+			return fmt.Errorf("winsvc.TriggerStart: %v", err)
+		}
+	}
+	cfg, err := p.GetServiceConfig(name)
+	if err != nil {
+		return fmt.Errorf("winsvc.TriggerStart: %v", err)
+	}
+	if p.host == "" {
+		for _, t := range cfg.Triggers {
+			if t.Type == TriggerCustom && t.Action == TriggerActionStart && t.Subtype != "" {
+				if err := fireTrigger(t); err != nil {
+					return fmt.Errorf("winsvc.TriggerStart: could not fire trigger %s: %v", t.Subtype, err)
+				}
+				return nil
+			}
+		}
+	}
+	status, err := p.QueryStatus(name)
+	if err != nil {
+		return fmt.Errorf("winsvc.TriggerStart: %v", err)
+	}
+	if status.State != "Stopped" {
+		return nil
+	}
+	s, err := p.openService(name, windows.SERVICE_START)
+	if err != nil {
+		return fmt.Errorf("winsvc.TriggerStart: could not access service: %v", err)
+	}
+	defer s.Close()
+	err = p.retryAction(windows.ERROR_SERVICE_ALREADY_RUNNING, func() error { return s.Start() })
+	if err != nil && err != windows.ERROR_SERVICE_ALREADY_RUNNING {
+		return fmt.Errorf("winsvc.TriggerStart: could not start service: %v", err)
+	}
+	return nil
+}
+
+// fireTrigger writes an event with no payload to the ETW provider of
+// custom trigger t, which the service control manager listens to.
+func fireTrigger(t Trigger) error {
+	provider, err := windows.GUIDFromString(t.Subtype)
+	if err != nil {
+		return err
+	}
+	desc := eventDescriptor{level: 4} // TRACE_LEVEL_INFORMATION
+	for _, d := range t.Data {
+		switch {
+		case d.Type == TriggerDataLevel && len(d.Data) >= 1:
+			desc.level = d.Data[0]
+		case (d.Type == TriggerDataKeywordAny || d.Type == TriggerDataKeywordAll) && len(d.Data) >= 8:
+			desc.keyword |= binary.LittleEndian.Uint64(d.Data)
+		}
+	}
+	var h uint64
+	r, _, _ := procEventRegister.Call(uintptr(unsafe.Pointer(&provider)), 0, 0, uintptr(unsafe.Pointer(&h)))
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	// REGHANDLE is 64 bits, passed in two words on 32 bits systems
+	if unsafe.Sizeof(uintptr(0)) == 4 {
+		defer procEventUnregister.Call(uintptr(h), uintptr(h>>32))
+		r, _, _ = procEventWrite.Call(uintptr(h), uintptr(h>>32), uintptr(unsafe.Pointer(&desc)), 0, 0)
+	} else {
+		defer procEventUnregister.Call(uintptr(h))
+		r, _, _ = procEventWrite.Call(uintptr(h), uintptr(unsafe.Pointer(&desc)), 0, 0)
+	}
+	if r != 0 {
+		return windows.Errno(r)
+	}
+	return nil
+}
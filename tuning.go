@@ -0,0 +1,119 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PriorityClass is the scheduling priority class of a process. The values
+// match the Windows *_PRIORITY_CLASS constants.
+type PriorityClass uint32
+
+const (
+	PriorityIdle        PriorityClass = 0x40
+	PriorityBelowNormal PriorityClass = 0x4000
+	PriorityNormal      PriorityClass = 0x20
+	PriorityAboveNormal PriorityClass = 0x8000
+	PriorityHigh        PriorityClass = 0x80
+	PriorityRealtime    PriorityClass = 0x100
+)
+
+// IOPriority is the default I/O priority of a process.
+type IOPriority int
+
+const (
+	IOPriorityDefault IOPriority = iota // left unchanged
+	IOPriorityVeryLow
+	IOPriorityLow
+	IOPriorityNormal
+)
+
+// ProcessTuning is how the service process is scheduled once it is
+// Running (see WithProcessTuning). Zero fields are left unchanged.
+type ProcessTuning struct {
+	Affinity      uint64 // mask of the CPUs the process may run on, CPU 0 is bit 0
+	PriorityClass PriorityClass
+	IOPriority    IOPriority
+}
+
+// Parameters values read by WithProcessTuningFromParameters.
+const (
+	ParamCPUAffinity   = "CPUAffinity"   // CPU numbers such as "0,2-3", or a mask such as "0x0d"
+	ParamPriorityClass = "PriorityClass" // Idle, BelowNormal, Normal, AboveNormal, High or Realtime
+	ParamIOPriority    = "IOPriority"    // VeryLow, Low or Normal
+)
+
+// tuningFromParameters overrides t by the tuning values of params.
+func tuningFromParameters(t ProcessTuning, params map[string]string) (ProcessTuning, error) {
+	if s := params[ParamCPUAffinity]; s != "" {
+		mask, err := parseAffinity(s)
+		if err != nil {
+			return t, err
+		}
+		t.Affinity = mask
+	}
+	if s := params[ParamPriorityClass]; s != "" {
+		classes := map[string]PriorityClass{
+			"idle":        PriorityIdle,
+			"belownormal": PriorityBelowNormal,
+			"normal":      PriorityNormal,
+			"abovenormal": PriorityAboveNormal,
+			"high":        PriorityHigh,
+			"realtime":    PriorityRealtime,
+		}
+		c, ok := classes[strings.ToLower(s)]
+		if !ok {
+			return t, fmt.Errorf("winsvc: unknown %s %q", ParamPriorityClass, s)
+		}
+		t.PriorityClass = c
+	}
+	if s := params[ParamIOPriority]; s != "" {
+		priorities := map[string]IOPriority{
+			"verylow": IOPriorityVeryLow,
+			"low":     IOPriorityLow,
+			"normal":  IOPriorityNormal,
+		}
+		p, ok := priorities[strings.ToLower(s)]
+		if !ok {
+			return t, fmt.Errorf("winsvc: unknown %s %q", ParamIOPriority, s)
+		}
+		t.IOPriority = p
+	}
+	return t, nil
+}
+
+// parseAffinity parses a hexadecimal CPU mask ("0x0d"), or a list of CPU
+// numbers and ranges ("0,2-3").
+func parseAffinity(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		mask, err := strconv.ParseUint(s[2:], 16, 64)
+		if err != nil {
+			return 0, fmt.Errorf("winsvc: invalid %s %q", ParamCPUAffinity, s)
+		}
+		return mask, nil
+	}
+	var mask uint64
+	for _, part := range strings.Split(s, ",") {
+		lo, hi := strings.TrimSpace(part), ""
+		if i := strings.Index(lo, "-"); i >= 0 {
+			lo, hi = strings.TrimSpace(lo[:i]), strings.TrimSpace(lo[i+1:])
+		} else {
+			hi = lo
+		}
+		first, err1 := strconv.Atoi(lo)
+		last, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || first < 0 || last > 63 || first > last {
+			return 0, fmt.Errorf("winsvc: invalid %s %q", ParamCPUAffinity, s)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			mask |= 1 << uint(cpu)
+		}
+	}
+	return mask, nil
+}
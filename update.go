@@ -0,0 +1,278 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func UpdateService(appPath, name string, cfg ServiceConfig) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.UpdateService(appPath, name, cfg)
+}
+
+func SetStartType(name string, t StartType) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.SetStartType(name, t)
+}
+
+// SetStartType changes when service name is started, without reinstalling it.
+func (p *Manager) SetStartType(name string, t StartType) error {
+	return p.updateConfig(name, func(c *mgr.Config) {
+		c.StartType, c.DelayedAutoStart = toMgrStartType(t)
+	})
+}
+
+func SetDescription(name, desc string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.SetDescription(name, desc)
+}
+
+func SetDisplayName(name, displayName string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.SetDisplayName(name, displayName)
+}
+
+func SetDependencies(name string, deps []string) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.SetDependencies(name, deps)
+}
+
+func SetErrorControl(name string, ec ErrorControl) error {
+	m, err := Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	return m.SetErrorControl(name, ec)
+}
+
+// SetDescription changes the description of service name. Like the other
+// single setting changes, it applies at once, even to a running service,
+// which keeps running.
+func (p *Manager) SetDescription(name, desc string) error {
+	if err := p.validateResourceString(desc); err != nil {
+		return err
+	}
+	return p.updateConfig(name, func(c *mgr.Config) {
+		c.Description = desc
+	})
+}
+
+// SetDisplayName changes the name services.msc shows for service name.
+func (p *Manager) SetDisplayName(name, displayName string) error {
+	if err := p.validateResourceString(displayName); err != nil {
+		return err
+	}
+	return p.updateConfig(name, func(c *mgr.Config) {
+		c.DisplayName = displayName
+	})
+}
+
+// SetDependencies replaces the services and groups (prefixed with "+")
+// started before service name; empty deps removes them all. A running
+// service is not affected until it is started again.
+func (p *Manager) SetDependencies(name string, deps []string) error {
+	if len(deps) == 0 {
+		return p.clearDependencies(name)
+	}
+	return p.updateConfig(name, func(c *mgr.Config) {
+		c.Dependencies = append([]string{}, deps...)
+	})
+}
+
+// clearDependencies removes the dependencies of service name, which
+// updateConfig cannot do: no dependencies mean no change to mgr.
+func (p *Manager) clearDependencies(name string) (err error) {
+	defer func() { p.audit("Update", name, nil, nil, err) }()
+	release, err := p.lockInstall(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+	s, err := p.openService(name, windows.SERVICE_CHANGE_CONFIG)
+	if err != nil {
+		return fmt.Errorf("winsvc.UpdateService: could not access service: %v", err)
+	}
+	defer s.Close()
+	empty := []uint16{0, 0}
+	err = windows.ChangeServiceConfig(s.Handle, windows.SERVICE_NO_CHANGE, windows.SERVICE_NO_CHANGE, windows.SERVICE_NO_CHANGE,
+		nil, nil, nil, &empty[0], nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("winsvc.UpdateService: could not update config: %v", err)
+	}
+	return nil
+}
+
+// SetErrorControl changes the severity of a failure of service name to
+// start at boot.
+func (p *Manager) SetErrorControl(name string, ec ErrorControl) error {
+	return p.updateConfig(name, func(c *mgr.Config) {
+		c.ErrorControl = uint32(ec)
+	})
+}
+
+// validateResourceString checks s if it is a resource reference, as the
+// install functions do.
+func (p *Manager) validateResourceString(s string) error {
+	if p.host != "" {
+		return nil
+	}
+	if err := validateResourceStrings(&ServiceConfig{DisplayName: s}); err != nil {
+		return fmt.Errorf("winsvc.UpdateService: %v", err)
+	}
+	return nil
+}
+
+// UpdateService reconfigures the installed service name as cfg, as if it
+// had been installed by InstallWithConfig. An empty appPath (and empty
+// cfg.BinaryPath) keeps the binary and its arguments, an empty
+// cfg.Account keeps the account (use AccountLocalSystem to switch back
+// to LocalSystem), an empty cfg.Password keeps the password, empty
+// cfg.Dependencies keep the dependencies, an empty cfg.Security keeps
+// the security descriptor and a zero cfg.PreshutdownTimeout keeps the
+// timeout. The event source is not changed.
+func (p *Manager) UpdateService(appPath, name string, cfg ServiceConfig) error {
+	release, err := p.lockInstall(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if appPath == "" {
+		appPath = cfg.BinaryPath
+	}
+	if p.host == "" {
+		if err := validateResourceStrings(&cfg); err != nil {
+			return fmt.Errorf("winsvc.UpdateService: %v", err)
+		}
+	}
+	if err := expandConfig(name, appPath, &cfg); err != nil {
+		return fmt.Errorf("winsvc.UpdateService: %v", err)
+	}
+	err = p.updateConfig(name, func(c *mgr.Config) {
+		binaryPath := c.BinaryPathName
+		*c = toMgrConfig(cfg)
+		c.BinaryPathName = binaryPath
+		if appPath != "" {
+			c.BinaryPathName = windows.ComposeCommandLine(append([]string{appPath}, cfg.Args...))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	// restart failure actions need the right to start the service
+	access := uint32(windows.SERVICE_CHANGE_CONFIG | windows.SERVICE_START)
+	if cfg.Security != "" {
+		access |= windows.WRITE_DAC
+	}
+	s, err := p.openService(name, access)
+	if err != nil {
+		return fmt.Errorf("winsvc.UpdateService: could not access service: %v", err)
+	}
+	defer s.Close()
+	if cfg.Account != "" && normalizeAccount(cfg.Account) == AccountLocalSystem {
+		if err := clearPassword(s.Handle); err != nil {
+			return fmt.Errorf("winsvc.UpdateService: could not clear the password: %v", err)
+		}
+	}
+	if err := writeRecovery(s, cfg.Recovery); err != nil {
+		return fmt.Errorf("winsvc.UpdateService: could not set recovery actions: %v", err)
+	}
+	if err := setTriggers(s.Handle, cfg.Triggers); err != nil {
+		return fmt.Errorf("winsvc.UpdateService: could not set triggers: %v", err)
+	}
+	if cfg.PreshutdownTimeout > 0 {
+		if err := setPreshutdownTimeout(s.Handle, cfg.PreshutdownTimeout); err != nil {
+			return fmt.Errorf("winsvc.UpdateService: could not set preshutdown timeout: %v", err)
+		}
+	}
+	if cfg.Security != "" {
+		if err := setServiceSecurity(s, cfg.Security); err != nil {
+			return fmt.Errorf("winsvc.UpdateService: could not set security: %v", err)
+		}
+	}
+	if cfg.Account != "" && !cfg.SkipLogonRight {
+		if err := p.GrantLogonRight(cfg.Account); err != nil {
+			return fmt.Errorf("winsvc.UpdateService: %v", err)
+		}
+	}
+	if cfg.Account != "" {
+		if err := p.grantStatsAccess(name, cfg); err != nil {
+			return fmt.Errorf("winsvc.UpdateService: %v", err)
+		}
+	}
+	if len(cfg.Parameters) > 0 {
+		if err := p.SetParameters(name, cfg.Parameters); err != nil {
+			return fmt.Errorf("winsvc.UpdateService: %v", err)
+		}
+	}
+	return nil
+}
+
+// updateConfig reads the configuration of service name, lets fn change
+// it and writes it back, holding the install lock. The lock is shared
+// within the process, so UpdateService, which holds it, can call it.
+func (p *Manager) updateConfig(name string, fn func(c *mgr.Config)) (err error) {
+	var before, after *ServiceConfig
+	defer func() { p.audit("Update", name, before, after, err) }()
+	release, err := p.lockInstall(name)
+	if err != nil {
+		return err
+	}
+	defer release()
+	s, err := p.openService(name, windows.SERVICE_QUERY_CONFIG|windows.SERVICE_CHANGE_CONFIG)
+	if err != nil {
+		return fmt.Errorf("winsvc.UpdateService: could not access service: %v", err)
+	}
+	defer s.Close()
+	c, err := s.Config()
+	if err != nil {
+		return fmt.Errorf("winsvc.UpdateService: could not read config: %v", err)
+	}
+	before = auditConfig(fromMgrConfig(c))
+	fn(&c)
+	after = auditConfig(fromMgrConfig(c))
+	if err := s.UpdateConfig(c); err != nil {
+		return fmt.Errorf("winsvc.UpdateService: could not update config: %v", err)
+	}
+	return nil
+}
+
+// clearPassword removes the password the service kept from an account
+// it ran as before: LocalSystem takes an empty one, which UpdateConfig
+// cannot set as it leaves an empty password unchanged.
+func clearPassword(h windows.Handle) error {
+	empty, err := windows.UTF16PtrFromString("")
+	if err != nil {
+		return err
+	}
+	return windows.ChangeServiceConfig(h, windows.SERVICE_NO_CHANGE, windows.SERVICE_NO_CHANGE, windows.SERVICE_NO_CHANGE,
+		nil, nil, nil, nil, nil, empty, nil)
+}
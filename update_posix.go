@@ -0,0 +1,44 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+func SetStartType(name string, t StartType) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetStartType(name string, t StartType) error {
+	panic("winsvc: only support windows!")
+}
+func UpdateService(appPath, name string, cfg ServiceConfig) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) UpdateService(appPath, name string, cfg ServiceConfig) error {
+	panic("winsvc: only support windows!")
+}
+func SetDescription(name, desc string) error {
+	panic("winsvc: only support windows!")
+}
+func SetDisplayName(name, displayName string) error {
+	panic("winsvc: only support windows!")
+}
+func SetDependencies(name string, deps []string) error {
+	panic("winsvc: only support windows!")
+}
+func SetErrorControl(name string, ec ErrorControl) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetDescription(name, desc string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetDisplayName(name, displayName string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetDependencies(name string, deps []string) error {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) SetErrorControl(name string, ec ErrorControl) error {
+	panic("winsvc: only support windows!")
+}
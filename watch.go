@@ -0,0 +1,135 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// watchers routes the status change notifications of the service control
+// manager to the watches. Callbacks cannot be freed, so all watches share
+// one.
+var watchers struct {
+	sync.Mutex
+	callback uintptr
+	next     uintptr
+	wake     map[uintptr]chan struct{}
+}
+
+func watchCallback(notify uint32, context uintptr) uintptr {
+	watchers.Lock()
+	wake := watchers.wake[context]
+	watchers.Unlock()
+	if wake != nil {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	return 0
+}
+
+// addWatcher registers wake, returning its callback and context.
+func addWatcher(wake chan struct{}) (callback, id uintptr) {
+	watchers.Lock()
+	defer watchers.Unlock()
+	if watchers.callback == 0 {
+		watchers.callback = windows.NewCallback(watchCallback)
+		watchers.wake = make(map[uintptr]chan struct{})
+	}
+	watchers.next++
+	watchers.wake[watchers.next] = wake
+	return watchers.callback, watchers.next
+}
+
+func removeWatcher(id uintptr) {
+	watchers.Lock()
+	defer watchers.Unlock()
+	delete(watchers.wake, id)
+}
+
+// WatchService is like Manager.WatchService, on a connection of its own
+// which is closed when the watch ends.
+func WatchService(ctx context.Context, name string) (<-chan ServiceStatus, error) {
+	m, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+	ch, err := m.watch(ctx, name, func() { m.Disconnect() })
+	if err != nil {
+		m.Disconnect()
+		return nil, err
+	}
+	return ch, nil
+}
+
+// WatchService streams the status of service name: its current status,
+// then every change, as notified by the service control manager, until
+// ctx is canceled or the status cannot be queried, when the channel is
+// closed. A status not received yet when the next one arrives is
+// replaced by it, so a slow reader sees the latest one. It needs
+// Windows 8 or later.
+func (p *Manager) WatchService(ctx context.Context, name string) (<-chan ServiceStatus, error) {
+	return p.watch(ctx, name, nil)
+}
+
+func (p *Manager) watch(ctx context.Context, name string, done func()) (<-chan ServiceStatus, error) {
+	s, err := p.openService(name, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return nil, fmt.Errorf("winsvc.WatchService: could not access service: %v", err)
+	}
+	wake := make(chan struct{}, 1)
+	callback, id := addWatcher(wake)
+	var sub uintptr
+	if err := windows.SubscribeServiceChangeNotifications(s.Handle, windows.SC_EVENT_STATUS_CHANGE, callback, id, &sub); err != nil {
+		removeWatcher(id)
+		s.Close()
+		return nil, fmt.Errorf("winsvc.WatchService: could not subscribe to status changes: %v", err)
+	}
+	out := make(chan ServiceStatus, 1)
+	go func() {
+		defer func() {
+			windows.UnsubscribeServiceChangeNotifications(sub)
+			removeWatcher(id)
+			s.Close()
+			if done != nil {
+				done()
+			}
+			close(out)
+		}()
+		var last ServiceStatus
+		for first := true; ; first = false {
+			status, err := s.Query()
+			if err != nil {
+				return
+			}
+			if current := toServiceStatus(name, status); first || current != last {
+				last = current
+				select {
+				case out <- current:
+				default:
+					// replace the status not yet received
+					select {
+					case <-out:
+					default:
+					}
+					out <- current
+				}
+			}
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
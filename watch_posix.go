@@ -0,0 +1,18 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvc
+
+import (
+	"context"
+)
+
+func WatchService(ctx context.Context, name string) (<-chan ServiceStatus, error) {
+	panic("winsvc: only support windows!")
+}
+func (p *Manager) WatchService(ctx context.Context, name string) (<-chan ServiceStatus, error) {
+	panic("winsvc: only support windows!")
+}
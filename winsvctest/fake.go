@@ -0,0 +1,260 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package winsvctest provides helpers to test code using package winsvc.
+//
+// FakeSCM is an in-memory winsvc.SCM which works on any OS:
+//
+//	scm := winsvctest.NewFakeSCM()
+//	if err := deploy(scm); err != nil {
+//		t.Fatal(err)
+//	}
+//	if state, _ := scm.Query("myservice"); state != "Running" {
+//		t.Fatalf("myservice is %s", state)
+//	}
+package winsvctest
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chai2010/winsvc"
+)
+
+// FakeService is a service installed in a FakeSCM.
+type FakeService struct {
+	AppPath string
+	Config  winsvc.ServiceConfig
+	State   string // "Stopped", "Running" or "Paused"
+	Starts  int    // times the service was started
+}
+
+// FakeSCM is an in-memory service control manager. Services start and
+// stop immediately. The zero value is not usable, use NewFakeSCM.
+type FakeSCM struct {
+	// Fail, if not nil, is called before each operation, with its
+	// method name such as "Start"; a non-nil error fails the operation.
+	Fail func(op, name string) error
+
+	mu       sync.Mutex
+	services map[string]*FakeService
+	calls    []string
+}
+
+var _ winsvc.SCM = (*FakeSCM)(nil)
+
+func NewFakeSCM() *FakeSCM {
+	return &FakeSCM{services: make(map[string]*FakeService)}
+}
+
+// Service returns a copy of service name, or nil if not installed.
+func (p *FakeSCM) Service(name string) *FakeService {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.services[name]
+	if !ok {
+		return nil
+	}
+	c := *s
+	c.Config = copyConfig(s.Config)
+	return &c
+}
+
+// copyConfig returns a copy of cfg sharing none of its slices, maps or
+// pointers, so the services of a FakeSCM do not change behind its back.
+func copyConfig(cfg winsvc.ServiceConfig) winsvc.ServiceConfig {
+	cfg.Dependencies = copyStrings(cfg.Dependencies)
+	cfg.Args = copyStrings(cfg.Args)
+	if cfg.Recovery != nil {
+		r := *cfg.Recovery
+		r.Actions = append([]winsvc.RecoveryAction(nil), r.Actions...)
+		cfg.Recovery = &r
+	}
+	if cfg.Triggers != nil {
+		triggers := make([]winsvc.Trigger, len(cfg.Triggers))
+		for i, t := range cfg.Triggers {
+			if t.Data != nil {
+				data := make([]winsvc.TriggerData, len(t.Data))
+				for j, d := range t.Data {
+					d.Data = append([]byte(nil), d.Data...)
+					data[j] = d
+				}
+				t.Data = data
+			}
+			triggers[i] = t
+		}
+		cfg.Triggers = triggers
+	}
+	if cfg.EventLogConfig != nil {
+		c := *cfg.EventLogConfig
+		cfg.EventLogConfig = &c
+	}
+	cfg.Parameters = copyMap(cfg.Parameters)
+	cfg.Vars = copyMap(cfg.Vars)
+	return cfg
+}
+
+func copyStrings(ss []string) []string {
+	if ss == nil {
+		return nil
+	}
+	return append([]string{}, ss...)
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// Services returns the names of the installed services, sorted.
+func (p *FakeSCM) Services() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var names []string
+	for name := range p.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Calls returns the operations made so far, as "Start myservice".
+func (p *FakeSCM) Calls() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+// begin records operation op on service name and locks p, which the
+// caller unlocks. It returns the service, nil if it is not installed.
+func (p *FakeSCM) begin(op, name string) (*FakeService, error) {
+	var err error
+	if p.Fail != nil {
+		err = p.Fail(op, name)
+	}
+	p.mu.Lock()
+	p.calls = append(p.calls, op+" "+name)
+	if err != nil {
+		return nil, err
+	}
+	return p.services[name], nil
+}
+
+func (p *FakeSCM) get(op, name string) (*FakeService, error) {
+	s, err := p.begin(op, name)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("winsvctest: service %s is not installed", name)
+	}
+	return s, nil
+}
+
+func (p *FakeSCM) InstallWithConfig(appPath, name string, cfg winsvc.ServiceConfig) error {
+	s, err := p.begin("InstallWithConfig", name)
+	defer p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if s != nil {
+		return fmt.Errorf("winsvctest: service %s already exists", name)
+	}
+	if appPath == "" {
+		appPath = cfg.BinaryPath
+	}
+	cfg = copyConfig(cfg)
+	cfg.BinaryPath = appPath
+	cfg.Password = ""
+	p.services[name] = &FakeService{AppPath: appPath, Config: cfg, State: "Stopped"}
+	return nil
+}
+
+func (p *FakeSCM) Remove(name string) error {
+	_, err := p.get("Remove", name)
+	defer p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	delete(p.services, name)
+	return nil
+}
+
+func (p *FakeSCM) Start(name string) error {
+	s, err := p.get("Start", name)
+	defer p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if s.Config.StartType == winsvc.StartTypeDisabled {
+		return fmt.Errorf("winsvctest: service %s is disabled", name)
+	}
+	if s.State != "Stopped" {
+		return fmt.Errorf("winsvctest: service %s is already running", name)
+	}
+	s.State = "Running"
+	s.Starts++
+	return nil
+}
+
+func (p *FakeSCM) Stop(name string) error {
+	s, err := p.get("Stop", name)
+	defer p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if s.State == "Stopped" {
+		return fmt.Errorf("winsvctest: service %s is not running", name)
+	}
+	s.State = "Stopped"
+	return nil
+}
+
+func (p *FakeSCM) StartAndWait(name string, timeout time.Duration) error {
+	return p.Start(name)
+}
+
+func (p *FakeSCM) StopAndWait(name string, timeout time.Duration) error {
+	return p.Stop(name)
+}
+
+func (p *FakeSCM) Query(name string) (string, error) {
+	s, err := p.get("Query", name)
+	defer p.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	return s.State, nil
+}
+
+func (p *FakeSCM) GetServiceConfig(name string) (winsvc.ServiceConfig, error) {
+	s, err := p.get("GetServiceConfig", name)
+	defer p.mu.Unlock()
+	if err != nil {
+		return winsvc.ServiceConfig{}, err
+	}
+	return copyConfig(s.Config), nil
+}
+
+func (p *FakeSCM) SetStartType(name string, t winsvc.StartType) error {
+	s, err := p.get("SetStartType", name)
+	defer p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.Config.StartType = t
+	return nil
+}
+
+func (p *FakeSCM) Disconnect() error {
+	return nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvctest_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/chai2010/winsvc"
+	"github.com/chai2010/winsvc/winsvctest"
+)
+
+func TestFakeSCMLifecycle(t *testing.T) {
+	scm := winsvctest.NewFakeSCM()
+	if err := scm.InstallWithConfig(`C:\svc.exe`, "svc", winsvc.ServiceConfig{Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := scm.InstallWithConfig(`C:\svc.exe`, "svc", winsvc.ServiceConfig{}); err == nil {
+		t.Fatal("second install of svc succeeded")
+	}
+	for _, step := range []struct {
+		op   func(name string) error
+		want string
+	}{
+		{scm.Start, "Running"},
+		{scm.Stop, "Stopped"},
+	} {
+		if err := step.op("svc"); err != nil {
+			t.Fatal(err)
+		}
+		if state, err := scm.Query("svc"); err != nil || state != step.want {
+			t.Fatalf("svc is %s (%v), want %s", state, err, step.want)
+		}
+	}
+	if err := scm.Stop("svc"); err == nil {
+		t.Fatal("stopping a stopped service succeeded")
+	}
+	s := scm.Service("svc")
+	if s.Starts != 1 || s.AppPath != `C:\svc.exe` || s.Config.BinaryPath != `C:\svc.exe` || s.Config.Password != "" {
+		t.Fatalf("service is %+v", s)
+	}
+	if err := scm.SetStartType("svc", winsvc.StartTypeDisabled); err != nil {
+		t.Fatal(err)
+	}
+	if err := scm.Start("svc"); err == nil {
+		t.Fatal("starting a disabled service succeeded")
+	}
+	if err := scm.Remove("svc"); err != nil {
+		t.Fatal(err)
+	}
+	if s := scm.Service("svc"); s != nil {
+		t.Fatalf("removed service is %+v", s)
+	}
+	want := []string{
+		"InstallWithConfig svc",
+		"InstallWithConfig svc",
+		"Start svc",
+		"Query svc",
+		"Stop svc",
+		"Query svc",
+		"Stop svc",
+		"SetStartType svc",
+		"Start svc",
+		"Remove svc",
+	}
+	if calls := scm.Calls(); !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls are %q, want %q", calls, want)
+	}
+}
+
+func TestFakeSCMFail(t *testing.T) {
+	scm := winsvctest.NewFakeSCM()
+	errDenied := errors.New("access denied")
+	scm.Fail = func(op, name string) error {
+		if op == "Start" {
+			return errDenied
+		}
+		return nil
+	}
+	if err := scm.InstallWithConfig(`C:\svc.exe`, "svc", winsvc.ServiceConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := scm.Start("svc"); err != errDenied {
+		t.Fatalf("Start failed with %v, want %v", err, errDenied)
+	}
+	if state, _ := scm.Query("svc"); state != "Stopped" {
+		t.Fatalf("svc is %s after a failed start", state)
+	}
+}
+
+// The configs passed to and returned by a FakeSCM do not alias the one
+// it keeps.
+func TestFakeSCMConfigCopy(t *testing.T) {
+	scm := winsvctest.NewFakeSCM()
+	cfg := winsvc.ServiceConfig{
+		Args:         []string{"-v"},
+		Dependencies: []string{"Tcpip"},
+		Recovery:     &winsvc.RecoveryConfig{Actions: []winsvc.RecoveryAction{{Type: winsvc.RecoveryRestart}}},
+		Triggers: []winsvc.Trigger{{
+			Type: winsvc.TriggerIPAddressAvailability,
+			Data: []winsvc.TriggerData{{Data: []byte{1}}},
+		}},
+		EventLogConfig: &winsvc.EventLogConfig{MaxSize: 1 << 20},
+		Parameters:     map[string]string{"Port": "80"},
+		Vars:           map[string]string{"Env": "prod"},
+	}
+	if err := scm.InstallWithConfig(`C:\svc.exe`, "svc", cfg); err != nil {
+		t.Fatal(err)
+	}
+	want, err := scm.GetServiceConfig("svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutate := func(c *winsvc.ServiceConfig) {
+		c.Args[0] = "-q"
+		c.Dependencies[0] = "Dnscache"
+		c.Recovery.Actions[0].Type = winsvc.RecoveryReboot
+		c.Triggers[0].Data[0].Data[0] = 2
+		c.EventLogConfig.MaxSize = 0
+		c.Parameters["Port"] = "8080"
+		c.Vars["Env"] = "dev"
+	}
+	mutate(&cfg)
+	got, _ := scm.GetServiceConfig("svc")
+	mutate(&got)
+	mutate(&scm.Service("svc").Config)
+	if got, _ := scm.GetServiceConfig("svc"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("config is %+v, want %+v", got, want)
+	}
+	if want.Args[0] != "-v" || want.Parameters["Port"] != "80" {
+		t.Fatalf("config is %+v", want)
+	}
+}
+
+// deploy installs and starts name as code written against winsvc.SCM does.
+func deploy(scm winsvc.SCM, name string) error {
+	cfg := winsvc.ServiceConfig{
+		DisplayName:  name,
+		StartType:    winsvc.StartTypeManual,
+		Dependencies: []string{"Tcpip"},
+		Args:         []string{"-name", name},
+	}
+	if err := scm.InstallWithConfig(`C:\svc\`+name+".exe", name, cfg); err != nil {
+		return err
+	}
+	return scm.StartAndWait(name, 0)
+}
+
+func TestFakeSCMConcurrentInstallQuery(t *testing.T) {
+	scm := winsvctest.NewFakeSCM()
+	const n = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("svc%d", i)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := deploy(scm, name); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			// the service may not be installed yet
+			for j := 0; j < 10; j++ {
+				scm.Query(name)
+				scm.GetServiceConfig(name)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if got := len(scm.Services()); got != n {
+		t.Fatalf("got %d services, want %d", got, n)
+	}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("svc%d", i)
+		if state, err := scm.Query(name); err != nil || state != "Running" {
+			t.Errorf("Query(%s) = %q, %v, want Running", name, state, err)
+		}
+	}
+}
+
+func TestFakeSCMConcurrentInstallSameName(t *testing.T) {
+	scm := winsvctest.NewFakeSCM()
+	const n = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	installed := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := scm.InstallWithConfig(`C:\svc\dup.exe`, "dup", winsvc.ServiceConfig{}); err == nil {
+				mu.Lock()
+				installed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if installed != 1 {
+		t.Fatalf("%d concurrent installs of the same service succeeded, want 1", installed)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvctest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chai2010/winsvc"
+)
+
+// Harness is a throwaway service installed for an end-to-end test on a
+// real Windows machine. It needs administrator rights. The service is
+// stopped and removed when the test ends, even if it fails.
+type Harness struct {
+	Name    string // unique service name
+	Timeout time.Duration
+
+	tb testing.TB
+	m  *winsvc.Manager
+}
+
+// NewHarness installs appPath as a uniquely named service configured as
+// cfg. On other systems than Windows it skips the test.
+func NewHarness(tb testing.TB, appPath string, cfg winsvc.ServiceConfig) *Harness {
+	tb.Helper()
+	m, err := winsvc.Connect()
+	if err != nil {
+		tb.Fatalf("winsvctest: could not connect to the service control manager: %v", err)
+	}
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		m.Disconnect()
+		tb.Fatalf("winsvctest: could not make a service name: %v", err)
+	}
+	h := &Harness{Name: "winsvctest-" + hex.EncodeToString(b[:]), Timeout: 30 * time.Second, tb: tb, m: m}
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = h.Name
+	}
+	if cfg.StartType == winsvc.StartTypeAutomatic {
+		cfg.StartType = winsvc.StartTypeManual
+	}
+	tb.Cleanup(h.cleanup)
+	if err := m.InstallWithConfig(appPath, h.Name, cfg); err != nil {
+		tb.Fatalf("winsvctest: could not install %s: %v", h.Name, err)
+	}
+	return h
+}
+
+func (h *Harness) cleanup() {
+	defer h.m.Disconnect()
+	if state, err := h.m.Query(h.Name); err == nil && state != "Stopped" {
+		h.m.StopAndWait(h.Name, h.Timeout)
+	}
+	h.m.Remove(h.Name)
+}
+
+// Start starts the service and waits until it is Running.
+func (h *Harness) Start() {
+	h.tb.Helper()
+	if err := h.m.StartAndWait(h.Name, h.Timeout); err != nil {
+		h.tb.Fatalf("winsvctest: could not start %s: %v", h.Name, err)
+	}
+}
+
+// Stop stops the service and waits until it is Stopped.
+func (h *Harness) Stop() {
+	h.tb.Helper()
+	if err := h.m.StopAndWait(h.Name, h.Timeout); err != nil {
+		h.tb.Fatalf("winsvctest: could not stop %s: %v", h.Name, err)
+	}
+}
+
+// Remove removes the service before the end of the test.
+func (h *Harness) Remove() {
+	h.tb.Helper()
+	if err := h.m.Remove(h.Name); err != nil {
+		h.tb.Fatalf("winsvctest: could not remove %s: %v", h.Name, err)
+	}
+}
+
+// AssertState fails the test unless the service is in state want, such
+// as "Running".
+func (h *Harness) AssertState(want string) {
+	h.tb.Helper()
+	state, err := h.m.Query(h.Name)
+	if err != nil {
+		h.tb.Fatalf("winsvctest: could not query %s: %v", h.Name, err)
+	}
+	if state != want {
+		h.tb.Fatalf("winsvctest: %s is %s, want %s", h.Name, state, want)
+	}
+}
+
+// Lifecycle runs start, query Running, stop, query Stopped and remove.
+func (h *Harness) Lifecycle() {
+	h.tb.Helper()
+	h.AssertState("Stopped")
+	h.Start()
+	h.AssertState("Running")
+	h.Stop()
+	h.AssertState("Stopped")
+	h.Remove()
+}
+
+// BuildHelper builds the main package pkg, such as "./testdata/helper",
+// into a temporary directory of the test and returns the binary path.
+func BuildHelper(tb testing.TB, pkg string) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "helper.exe")
+	out, err := exec.Command("go", "build", "-o", path, pkg).CombinedOutput()
+	if err != nil {
+		tb.Fatalf("winsvctest: could not build %s: %v\n%s", pkg, err, out)
+	}
+	return path
+}
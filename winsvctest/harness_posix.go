@@ -0,0 +1,36 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows
+
+package winsvctest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chai2010/winsvc"
+)
+
+type Harness struct {
+	Name    string
+	Timeout time.Duration
+}
+
+// NewHarness skips the test, services need Windows.
+func NewHarness(tb testing.TB, appPath string, cfg winsvc.ServiceConfig) *Harness {
+	tb.Skip("winsvctest: Harness needs Windows")
+	return nil
+}
+
+func (h *Harness) Start()                  {}
+func (h *Harness) Stop()                   {}
+func (h *Harness) Remove()                 {}
+func (h *Harness) AssertState(want string) {}
+func (h *Harness) Lifecycle()              {}
+
+func BuildHelper(tb testing.TB, pkg string) string {
+	tb.Skip("winsvctest: Harness needs Windows")
+	return ""
+}
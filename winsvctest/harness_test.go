@@ -0,0 +1,25 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package winsvctest_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chai2010/winsvc"
+	"github.com/chai2010/winsvc/winsvctest"
+)
+
+// TestHarnessLifecycle installs, starts, stops and removes a real
+// service. It needs administrator rights, so it only runs with
+// WINSVCTEST_E2E set.
+func TestHarnessLifecycle(t *testing.T) {
+	if os.Getenv("WINSVCTEST_E2E") == "" {
+		t.Skip("set WINSVCTEST_E2E to run the end-to-end tests as administrator")
+	}
+	path := winsvctest.BuildHelper(t, "./testdata/helper")
+	h := winsvctest.NewHarness(t, path, winsvc.ServiceConfig{})
+	h.Lifecycle()
+}
@@ -0,0 +1,23 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Helper is the service the tests of package winsvctest install: it runs
+// until it is asked to stop.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/chai2010/winsvc"
+)
+
+func main() {
+	err := winsvc.RunAsServiceContext("winsvctest-helper", func(ctx context.Context) {
+		<-ctx.Done()
+	}, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
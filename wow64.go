@@ -0,0 +1,132 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// keyView is added to the access of the registry keys this package
+// opens, so that a 32-bit process on 64-bit Windows uses the keys the
+// service control manager and the 64-bit services do.
+const keyView = registry.WOW64_64KEY
+
+var wow64 struct {
+	once    sync.Once
+	on      bool
+	machine uint16 // of the process, IMAGE_FILE_MACHINE_*
+}
+
+const (
+	imageFileMachineI386  = 0x014c
+	imageFileMachineARMNT = 0x01c4
+)
+
+// isWow64 reports whether this is a 32-bit process on 64-bit Windows,
+// x86 or 32-bit ARM, whose file system and registry views are
+// redirected. x64 and ARM64EC processes on ARM64 Windows are not.
+func isWow64() bool {
+	wow64.once.Do(func() {
+		var process, native uint16
+		if err := windows.IsWow64Process2(windows.CurrentProcess(), &process, &native); err == nil {
+			wow64.on = process != 0 // IMAGE_FILE_MACHINE_UNKNOWN
+			wow64.machine = process
+			return
+		}
+		// before Windows 10 1511, only x86 runs on WOW64
+		windows.IsWow64Process(windows.CurrentProcess(), &wow64.on)
+		wow64.machine = imageFileMachineI386
+	})
+	return wow64.on
+}
+
+// systemDirs returns the System32 directory and, for a WOW64 process,
+// the directories its accesses to System32 really go to (SysWOW64 or
+// SysArm32) and the alias of the real System32 (Sysnative).
+func systemDirs() (system32, redirected, sysnative string) {
+	root, err := windows.GetSystemWindowsDirectory()
+	if err != nil {
+		root = os.Getenv("SystemRoot")
+	}
+	return systemDirsOf(root, isWow64(), wow64.machine)
+}
+
+// systemDirsOf returns the systemDirs of a process of machine under the
+// Windows directory root, running on WOW64 if wow is set.
+func systemDirsOf(root string, wow bool, machine uint16) (system32, redirected, sysnative string) {
+	system32 = filepath.Join(root, "System32")
+	if wow {
+		redirected = filepath.Join(root, "SysWOW64")
+		if machine == imageFileMachineARMNT {
+			redirected = filepath.Join(root, "SysArm32")
+		}
+		sysnative = filepath.Join(root, "Sysnative")
+	}
+	return
+}
+
+// hasDirPrefix reports whether path is in dir, and returns the rest.
+func hasDirPrefix(path, dir string) (string, bool) {
+	if dir == "" || len(path) <= len(dir) || !strings.EqualFold(path[:len(dir)], dir) || !os.IsPathSeparator(path[len(dir)]) {
+		return "", false
+	}
+	return path[len(dir):], true
+}
+
+// nativePath returns the path this process accesses path of the service
+// control manager at: in a WOW64 process, a path in System32 is reached
+// through Sysnative.
+func nativePath(path string) string {
+	system32, _, sysnative := systemDirs()
+	return rebasePath(path, system32, sysnative)
+}
+
+// servicePath returns the path the service control manager finds file
+// path of this process at: in a WOW64 process, a file of System32 is
+// really in SysWOW64 (or SysArm32).
+func servicePath(path string) string {
+	system32, redirected, _ := systemDirs()
+	return rebasePath(path, system32, redirected)
+}
+
+// rebasePath moves path from directory from to directory to, if it is
+// in from and to is not empty.
+func rebasePath(path, from, to string) string {
+	if rest, ok := hasDirPrefix(path, from); ok && to != "" {
+		return to + rest
+	}
+	return path
+}
+
+// expandServicePath expands the environment variables of path as the
+// service control manager does, with the values of 64-bit processes.
+func expandServicePath(path string) (string, error) {
+	if isWow64() {
+		path = nativeEnvVars(path)
+	}
+	return registry.ExpandString(path)
+}
+
+// nativeEnvVars replaces the variables of path whose values differ in
+// WOW64 processes by those holding the 64-bit values.
+func nativeEnvVars(path string) string {
+	for _, v := range [][2]string{
+		{"%ProgramFiles%", "%ProgramW6432%"},
+		{"%CommonProgramFiles%", "%CommonProgramW6432%"},
+	} {
+		if i := strings.Index(strings.ToLower(path), strings.ToLower(v[0])); i >= 0 {
+			path = path[:i] + v[1] + path[i+len(v[0]):]
+		}
+	}
+	return path
+}
@@ -0,0 +1,108 @@
+// Copyright 2012 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build windows
+
+package winsvc
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const imageFileMachineAMD64 = 0x8664
+
+func TestHasDirPrefix(t *testing.T) {
+	for _, tt := range []struct {
+		path, dir string
+		rest      string
+		ok        bool
+	}{
+		{`C:\Windows\System32\svchost.exe`, `C:\Windows\System32`, `\svchost.exe`, true},
+		{`c:\windows\system32\drivers\x.sys`, `C:\Windows\System32`, `\drivers\x.sys`, true},
+		{`C:\Windows\System32/svchost.exe`, `C:\Windows\System32`, `/svchost.exe`, true},
+		{`C:\Windows\System32`, `C:\Windows\System32`, "", false},
+		{`C:\Windows\System32x\a.exe`, `C:\Windows\System32`, "", false},
+		{`C:\Windows\SysWOW64\a.exe`, `C:\Windows\System32`, "", false},
+		{`C:\a.exe`, "", "", false},
+	} {
+		rest, ok := hasDirPrefix(tt.path, tt.dir)
+		if rest != tt.rest || ok != tt.ok {
+			t.Errorf("hasDirPrefix(%q, %q) = %q, %v, want %q, %v", tt.path, tt.dir, rest, ok, tt.rest, tt.ok)
+		}
+	}
+}
+
+func TestWow64Paths(t *testing.T) {
+	const (
+		root = `C:\Windows`
+		exe  = `C:\Windows\System32\agent.exe`
+	)
+	for _, tt := range []struct {
+		name    string
+		wow     bool
+		machine uint16
+		service string // servicePath(exe)
+		native  string // nativePath(exe)
+	}{
+		{"x64 native", false, imageFileMachineAMD64, exe, exe},
+		{"arm64 native", false, 0, exe, exe},
+		{"x86 on x64", true, imageFileMachineI386, `C:\Windows\SysWOW64\agent.exe`, `C:\Windows\Sysnative\agent.exe`},
+		{"x86 on arm64", true, imageFileMachineI386, `C:\Windows\SysWOW64\agent.exe`, `C:\Windows\Sysnative\agent.exe`},
+		{"arm32 on arm64", true, imageFileMachineARMNT, `C:\Windows\SysArm32\agent.exe`, `C:\Windows\Sysnative\agent.exe`},
+	} {
+		system32, redirected, sysnative := systemDirsOf(root, tt.wow, tt.machine)
+		if got := rebasePath(exe, system32, redirected); got != tt.service {
+			t.Errorf("%s: servicePath(%q) = %q, want %q", tt.name, exe, got, tt.service)
+		}
+		if got := rebasePath(exe, system32, sysnative); got != tt.native {
+			t.Errorf("%s: nativePath(%q) = %q, want %q", tt.name, exe, got, tt.native)
+		}
+		// paths out of System32 are the same in every view
+		other := `C:\Program Files\Agent\agent.exe`
+		if got := rebasePath(other, system32, redirected); got != other {
+			t.Errorf("%s: servicePath(%q) = %q, want it unchanged", tt.name, other, got)
+		}
+	}
+}
+
+func TestNativeEnvVars(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{`%ProgramFiles%\Agent\agent.exe`, `%ProgramW6432%\Agent\agent.exe`},
+		{`%programfiles%\Agent\agent.exe`, `%ProgramW6432%\Agent\agent.exe`},
+		{`%CommonProgramFiles%\Agent\a.dll`, `%CommonProgramW6432%\Agent\a.dll`},
+		{`%SystemRoot%\System32\agent.exe`, `%SystemRoot%\System32\agent.exe`},
+		{`C:\Agent\agent.exe`, `C:\Agent\agent.exe`},
+	} {
+		if got := nativeEnvVars(tt.in); got != tt.want {
+			t.Errorf("nativeEnvVars(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandServicePath(t *testing.T) {
+	got, err := expandServicePath(`%SystemRoot%\System32\agent.exe`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := os.Getenv("SystemRoot") + `\System32\agent.exe`; !strings.EqualFold(got, want) {
+		t.Errorf("expandServicePath = %q, want %q", got, want)
+	}
+	got, err = expandServicePath(`%ProgramFiles%\Agent\agent.exe`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the service control manager, a 64-bit process, sees the 64-bit
+	// Program Files even from a WOW64 installer
+	want := os.Getenv("ProgramFiles")
+	if isWow64() {
+		want = os.Getenv("ProgramW6432")
+	}
+	if want += `\Agent\agent.exe`; !strings.EqualFold(got, want) {
+		t.Errorf("expandServicePath = %q, want %q", got, want)
+	}
+}